@@ -0,0 +1,40 @@
+package keep
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Anchor defines from which end of the timeline the levels of a Jailhouse are laid out.
+type Anchor int8
+
+const (
+	// AnchorYoungest starts every level at the youngest element not yet processed and walks backwards in time.
+	AnchorYoungest Anchor = iota
+	// AnchorOldest lays out a fixed grid for every level starting at an epoch (the oldest element by default)
+	// and keeps the oldest element of each grid cell, so representatives do not shift when new elements arrive.
+	AnchorOldest
+)
+
+var anchorNames = map[Anchor]string{
+	AnchorYoungest: "youngest",
+	AnchorOldest:   "oldest",
+}
+
+// String implements the Stringer interface.
+func (x Anchor) String() string {
+	if name, ok := anchorNames[x]; ok {
+		return name
+	}
+	return fmt.Sprintf("Anchor(%d)", x)
+}
+
+// ParseAnchor converts a string (case-insensitive) to an Anchor.
+func ParseAnchor(name string) (Anchor, error) {
+	for anchor, anchorName := range anchorNames {
+		if strings.EqualFold(name, anchorName) {
+			return anchor, nil
+		}
+	}
+	return AnchorYoungest, fmt.Errorf("%s is not a valid Anchor, try [youngest, oldest]", name)
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/jojomi/keep"
+	"github.com/spf13/cobra"
+)
+
+func getApplyCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "apply plan-file",
+		Short: "remove exactly the elements of a plan written by keep plan",
+		Args:  cobra.ExactArgs(1),
+		RunE:  runApply,
+	}
+
+	addLockFlags(cmd.Flags())
+
+	return cmd
+}
+
+func runApply(cmd *cobra.Command, args []string) error {
+	cmd.SilenceUsage = true
+	err := applyEnvironment(cmd.Flags())
+	if err != nil {
+		return withExitCode(exitUsage, err)
+	}
+	env, err := parseEnvApply(cmd, args)
+	if err != nil {
+		return withExitCode(exitUsage, err)
+	}
+
+	p, err := readPlan(env.Plan)
+	if err != nil {
+		return withExitCode(exitUsage, err)
+	}
+
+	count := 0
+	for _, target := range p.Targets {
+		fmt.Printf("\n%s (%s, planned %s by %s@%s):\n", target.Target, target.Action, p.Created.Format("2006-01-02 15:04:05"), p.User, p.Host)
+		if target.Action == "exec" {
+			fmt.Println(target.describeExec())
+		}
+		for _, element := range target.Elements {
+			fmt.Println(element.Path)
+			for _, companion := range element.Companions {
+				fmt.Println(companion.Path)
+			}
+		}
+		count += len(target.Elements)
+	}
+	if count == 0 {
+		fmt.Println("\nNothing to remove.")
+		if env.DetailedExitCodes {
+			return withExitCode(exitNothingRemoved, errNothingRemoved)
+		}
+		return nil
+	}
+
+	if !env.Force && !env.DryRun {
+		err = confirmRemoval(os.Stdout, count, env.ConfirmThreshold)
+		if err != nil {
+			return err
+		}
+	}
+
+	failed := 0
+	for _, target := range p.Targets {
+		targetFailed, err := applyPlanTarget(p, target, env, os.Stdout)
+		if err != nil {
+			return err
+		}
+		failed += targetFailed
+	}
+	if failed > 0 {
+		return withExitCode(exitPartialFailure, fmt.Errorf("%d of %d elements were not removed", failed, count))
+	}
+	return nil
+}
+
+// applyPlanTarget removes the elements of target still matching their fingerprint. Elements that changed or
+// vanished since planning are refused. It returns the number of elements not removed, errors stopping the whole
+// target (like a lock held by another process) are returned as error.
+func applyPlanTarget(p *plan, target planTarget, env EnvApply, w io.Writer) (int, error) {
+	var (
+		command  *execCommand
+		dispose  disposal
+		err      error
+		verified = make([]*keep.JailhouseTimeResource[fileElement], 0, len(target.Elements))
+		failed   = 0
+	)
+	if target.Action == "exec" {
+		command, err = parseExecCommand(target.Exec, target.ExecBatch)
+	} else {
+		dispose, err = newDisposal(target.Action, target.QuarantineDir, target.ArchiveDir)
+	}
+	if err != nil {
+		return 0, withExitCode(exitUsage, fmt.Errorf("plan for %s: %w", target.Target, err))
+	}
+
+	if env.Lock != "off" && !env.DryRun {
+		path, err := lockFilePath(target.Target, env.LockDir)
+		if err != nil {
+			return 0, err
+		}
+		lock, err := acquireLock(path, env.Lock, env.LockTimeout)
+		if err != nil {
+			return 0, withExitCode(exitLocked, err)
+		}
+		defer lock.Release()
+	}
+
+	var audit *auditLog
+	if !env.DryRun {
+		auditEnv := EnvRoot{
+			Action: target.Action,
+			Job:    target.Job,
+		}
+		audit, err = startAuditLog(env.AuditLog, auditEnv, target.Target, p.Requirements, p.ReferenceDate)
+		if err != nil {
+			return 0, err
+		}
+		defer audit.Close()
+	}
+
+	var (
+		sizes       = make(map[*keep.JailhouseTimeResource[fileElement]]int64)
+		memberSizes = make(map[string]int64)
+	)
+	for _, planned := range target.Elements {
+		file := fileElement{
+			Filename: planned.Path,
+			Time:     planned.Time,
+		}
+		size := planned.Size
+		memberSizes[planned.Path] = planned.Size
+		for _, companion := range planned.Companions {
+			file.Companions = append(file.Companions, companion.Path)
+			size += companion.Size
+			memberSizes[companion.Path] = companion.Size
+		}
+		element := keep.NewJailhouseTimeResource(file)
+		sizes[element] = size
+
+		// a group is only removed as a whole
+		err := verifyPlanElement(target.Target, planned)
+		for _, companion := range planned.Companions {
+			if err == nil {
+				err = verifyPlanElement(target.Target, companion)
+			}
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Refusing %s: %v\n", displayPath(file), err)
+			failed++
+			if auditErr := audit.Removed(element, size, err); auditErr != nil {
+				fmt.Fprintln(os.Stderr, auditErr)
+			}
+			continue
+		}
+		verified = append(verified, element)
+	}
+
+	if command != nil {
+		// the command is run for every member of a group, so the sizes are recorded per member
+		err = runExec(command, expandCompanions(verified), env.DryRun, w, func(element *keep.JailhouseTimeResource[fileElement], err error) {
+			if auditErr := audit.Removed(element, memberSizes[element.TimeResource.Filename], err); auditErr != nil {
+				fmt.Fprintln(os.Stderr, auditErr)
+			}
+			if err != nil {
+				failed++
+			}
+		})
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+		return failed, nil
+	}
+
+	for _, element := range verified {
+		if env.DryRun {
+			fmt.Fprintf(w, "[DRY-RUN] Would be %s %s...\n", disposalActions[target.Action], displayPath(element.TimeResource))
+			continue
+		}
+		err := disposeFile(dispose, target.Target, element.TimeResource, target.Dirs)
+		if auditErr := audit.Removed(element, sizes[element], err); auditErr != nil {
+			fmt.Fprintln(os.Stderr, auditErr)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed %s: %v\n", element.TimeResource.Filename, err)
+			failed++
+		}
+	}
+	return failed, nil
+}
+
+// verifyPlanElement returns an error if the element is outside of the target, no longer exists, or its content
+// changed since planning.
+func verifyPlanElement(root string, planned planElement) error {
+	err := ensureInside(root, planned.Path)
+	if err != nil {
+		return err
+	}
+	_, err = os.Lstat(planned.Path)
+	if err != nil {
+		return err
+	}
+	sum, err := fingerprint(planned.Path)
+	if err != nil {
+		return err
+	}
+	if sum != planned.Fingerprint {
+		return fmt.Errorf("fingerprint changed since planning")
+	}
+	return nil
+}
@@ -0,0 +1,283 @@
+package main
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/user"
+	"path/filepath"
+	"time"
+
+	"github.com/jojomi/keep"
+)
+
+// auditRecord is a single line of the audit log. Every run of a target starts with a record of type run, followed by
+// one of type removed or failed per element and a final one of type done.
+type auditRecord struct {
+	Type string    `json:"type"`
+	Run  string    `json:"run"`
+	Time time.Time `json:"time"`
+
+	// run
+	Host          string             `json:"host,omitempty"`
+	User          string             `json:"user,omitempty"`
+	Job           string             `json:"job,omitempty"`
+	Target        string             `json:"target,omitempty"`
+	Requirements  *keep.Requirements `json:"requirements,omitempty"`
+	ReferenceDate *time.Time         `json:"referenceDate,omitempty"`
+	Action        string             `json:"action,omitempty"`
+
+	// removed and failed, the path is the full name for git refs like refs/tags/nightly-2024-03-01
+	Path        string     `json:"path,omitempty"`
+	Companions  []string   `json:"companions,omitempty"`
+	Size        *int64     `json:"size,omitempty"`
+	ElementTime *time.Time `json:"elementTime,omitempty"`
+	Free        bool       `json:"free,omitempty"`
+	Error       string     `json:"error,omitempty"`
+
+	// done
+	Removed int `json:"removed,omitempty"`
+	Failed  int `json:"failed,omitempty"`
+}
+
+// auditLog appends the records of a run to an audit log file, see --audit-log. All methods are no-ops for a nil
+// auditLog, so callers need not check whether auditing is enabled.
+type auditLog struct {
+	file    *os.File
+	run     string
+	removed int
+	failed  int
+}
+
+// startAuditLog opens the audit log at path for appending and writes the run record. It returns nil if path is
+// empty.
+func startAuditLog(path string, env EnvRoot, root string, reqs keep.Requirements, referenceDate time.Time) (*auditLog, error) {
+	if path == "" {
+		return nil, nil
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o640)
+	if err != nil {
+		return nil, fmt.Errorf("opening audit log: %w", err)
+	}
+
+	id := make([]byte, 8)
+	_, err = rand.Read(id)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	x := &auditLog{
+		file: file,
+		run:  hex.EncodeToString(id),
+	}
+
+	host, _ := os.Hostname()
+	action := env.Action
+	if env.Exec != nil {
+		action = "exec"
+	}
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		absRoot = root
+	}
+	err = x.write(auditRecord{
+		Type:          "run",
+		Host:          host,
+		User:          currentUser(),
+		Job:           env.Job,
+		Target:        absRoot,
+		Requirements:  &reqs,
+		ReferenceDate: &referenceDate,
+		Action:        action,
+	})
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return x, nil
+}
+
+// Removed records the outcome of disposing of a free element of the given size, err is nil on success.
+func (x *auditLog) Removed(element *keep.JailhouseTimeResource[fileElement], size int64, err error) error {
+	if x == nil {
+		return nil
+	}
+	elementTime := element.GetTime()
+	record := auditRecord{
+		Path:        element.TimeResource.Filename,
+		Size:        &size,
+		ElementTime: &elementTime,
+		Free:        element.IsFree(),
+	}
+	if absPath, absErr := filepath.Abs(record.Path); absErr == nil {
+		record.Path = absPath
+	}
+	for _, companion := range element.TimeResource.Companions {
+		if absPath, absErr := filepath.Abs(companion); absErr == nil {
+			companion = absPath
+		}
+		record.Companions = append(record.Companions, companion)
+	}
+	return x.result(record, err)
+}
+
+// RemovedRef records the outcome of deleting a free git ref with the full name refName, err is nil on success.
+func (x *auditLog) RemovedRef(element *keep.JailhouseTimeResource[gitRef], refName string, err error) error {
+	if x == nil {
+		return nil
+	}
+	elementTime := element.GetTime()
+	return x.result(auditRecord{
+		Path:        refName,
+		ElementTime: &elementTime,
+		Free:        element.IsFree(),
+	}, err)
+}
+
+// result writes the record of a removed element, or of a failed one if err is not nil.
+func (x *auditLog) result(record auditRecord, err error) error {
+	record.Type = "removed"
+	if err != nil {
+		record.Type = "failed"
+		record.Error = err.Error()
+		x.failed++
+	} else {
+		x.removed++
+	}
+	return x.write(record)
+}
+
+// Close writes the done record and closes the audit log.
+func (x *auditLog) Close() error {
+	if x == nil {
+		return nil
+	}
+	err := x.write(auditRecord{
+		Type:    "done",
+		Removed: x.removed,
+		Failed:  x.failed,
+	})
+	if syncErr := x.file.Sync(); err == nil {
+		err = syncErr
+	}
+	if closeErr := x.file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// write appends a record as a single line, so records of concurrent runs do not interleave.
+func (x *auditLog) write(record auditRecord) error {
+	record.Run = x.run
+	record.Time = time.Now()
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	_, err = x.file.Write(append(data, '\n'))
+	if err != nil {
+		return fmt.Errorf("writing audit log: %w", err)
+	}
+	return nil
+}
+
+func currentUser() string {
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return os.Getenv("USER")
+}
+
+// elementSize returns the size of the file at path, or of all files below it for directory elements.
+func elementSize(path string, dir bool) int64 {
+	if !dir {
+		info, err := os.Lstat(path)
+		if err != nil {
+			return 0
+		}
+		return info.Size()
+	}
+
+	var size int64
+	_ = filepath.WalkDir(path, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		if info, err := d.Info(); err == nil {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size
+}
+
+// auditFilter selects records of type removed and failed from an audit log, see `keep log`.
+type auditFilter struct {
+	// Since and Until limit the time of removal, zero values are not checked.
+	Since time.Time
+	Until time.Time
+	// Patterns are glob patterns matched against the name and the path of the element, see matchesAnyGlob.
+	Patterns   []string
+	FailedOnly bool
+}
+
+// readAuditLog returns the records of type removed and failed of the audit log at path matching filter, each with
+// the run record it belongs to.
+func readAuditLog(path string, filter auditFilter) ([]auditRecord, map[string]auditRecord, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer file.Close()
+
+	var (
+		records = make([]auditRecord, 0)
+		runs    = make(map[string]auditRecord)
+		scanner = bufio.NewScanner(file)
+		line    = 0
+	)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line++
+		var record auditRecord
+		err := json.Unmarshal(scanner.Bytes(), &record)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+
+		switch record.Type {
+		case "run":
+			runs[record.Run] = record
+			continue
+		case "removed":
+			if filter.FailedOnly {
+				continue
+			}
+		case "failed":
+		default:
+			continue
+		}
+
+		if !filter.Since.IsZero() && record.Time.Before(filter.Since) {
+			continue
+		}
+		if !filter.Until.IsZero() && !record.Time.Before(filter.Until) {
+			continue
+		}
+		if len(filter.Patterns) > 0 {
+			matches, err := matchesAnyGlob(filter.Patterns, filepath.ToSlash(record.Path))
+			if err != nil {
+				return nil, nil, err
+			}
+			if !matches {
+				continue
+			}
+		}
+		records = append(records, record)
+	}
+	return records, runs, scanner.Err()
+}
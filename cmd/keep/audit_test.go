@@ -0,0 +1,99 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jojomi/keep"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuditLog(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "audit.jsonl")
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "a.sql"), []byte("12345"), 0o644))
+
+	// auditing is disabled without a path
+	audit, err := startAuditLog("", EnvRoot{}, dir, *keep.NewRequirements(), time.Now())
+	assert.NoError(t, err)
+	assert.Nil(t, audit)
+	assert.NoError(t, audit.Removed(nil, 0, nil))
+	assert.NoError(t, audit.Close())
+
+	elements := execElements(filepath.Join(dir, "a.sql"), filepath.Join(dir, "b.sql"))
+	start := time.Now()
+	for i := 0; i < 2; i++ {
+		audit, err = startAuditLog(path, EnvRoot{Action: "delete", Job: "pg"}, dir, *keep.NewRequirementsFromString("7 days"), start)
+		assert.NoError(t, err)
+		assert.NoError(t, audit.Removed(elements[0], elementSize(elements[0].TimeResource.Filename, false), nil))
+		assert.NoError(t, audit.Removed(elements[1], 0, errors.New("permission denied")))
+		assert.NoError(t, audit.Close())
+	}
+
+	records, runs, err := readAuditLog(path, auditFilter{})
+	assert.NoError(t, err)
+	assert.Len(t, runs, 2)
+	assert.Len(t, records, 4)
+	assert.Equal(t, "removed", records[0].Type)
+	assert.Equal(t, int64(5), *records[0].Size)
+	assert.True(t, records[0].Free)
+	assert.Equal(t, "failed", records[1].Type)
+	assert.Equal(t, "permission denied", records[1].Error)
+
+	run := runs[records[0].Run]
+	assert.Equal(t, "pg", run.Job)
+	assert.Equal(t, "delete", run.Action)
+	assert.Equal(t, "DAY=7", run.Requirements.String())
+	assert.NotEqual(t, records[0].Run, records[2].Run)
+
+	tests := []struct {
+		name   string
+		filter auditFilter
+		want   int
+	}{
+		{name: "failed", filter: auditFilter{FailedOnly: true}, want: 2},
+		{name: "name", filter: auditFilter{Patterns: []string{"a.*"}}, want: 2},
+		{name: "path", filter: auditFilter{Patterns: []string{filepath.ToSlash(dir) + "/b.sql"}}, want: 2},
+		{name: "since", filter: auditFilter{Since: time.Now().Add(time.Hour)}, want: 0},
+		{name: "until", filter: auditFilter{Until: start.Add(-time.Hour)}, want: 0},
+		{name: "range", filter: auditFilter{Since: start.Add(-time.Hour), Until: time.Now().Add(time.Hour)}, want: 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records, _, err := readAuditLog(path, tt.filter)
+			assert.NoError(t, err)
+			assert.Len(t, records, tt.want)
+		})
+	}
+}
+
+func TestParseLogTime(t *testing.T) {
+	got, err := parseLogTime("2024-03-01", false)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 3, 1, 0, 0, 0, 0, time.Local), got)
+
+	got, err = parseLogTime("2024-03-01", true)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 3, 2, 0, 0, 0, 0, time.Local), got)
+
+	got, err = parseLogTime("2024-03-01T12:00:00Z", true)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC), got)
+
+	got, err = parseLogTime("", true)
+	assert.NoError(t, err)
+	assert.True(t, got.IsZero())
+
+	_, err = parseLogTime("yesterday", false)
+	assert.Error(t, err)
+}
+
+func TestElementSize(t *testing.T) {
+	root, _ := snapshotTree(t)
+	assert.Equal(t, int64(1), elementSize(filepath.Join(root, "README"), false))
+	assert.Equal(t, int64(1), elementSize(filepath.Join(root, "daily.0"), true))
+	assert.Equal(t, int64(0), elementSize(filepath.Join(root, "missing"), false))
+}
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v3"
+)
+
+// defaultConfigFile is read by `keep run` and `keep config validate` without --config.
+const defaultConfigFile = "/etc/keep.yaml"
+
+// envPrefix starts the names of the environment variables setting flags, e.g. KEEP_DRY_RUN for --dry-run.
+const envPrefix = "KEEP_"
+
+// config is the content of a config file. Settings are named like the flags of the root command, the settings of
+// a job take precedence over the defaults.
+type config struct {
+	Defaults map[string]any `yaml:"defaults"`
+	Jobs     []configJob    `yaml:"jobs"`
+}
+
+// configJob is a single pruning job of a config file.
+type configJob struct {
+	Name string `yaml:"name"`
+	// Path is a single target directory or a list of them.
+	Path     any            `yaml:"path"`
+	Settings map[string]any `yaml:",inline"`
+}
+
+// loadConfig reads and decodes the config file at path, checking job names and paths.
+func loadConfig(path string) (*config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var c config
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	err = decoder.Decode(&c)
+	if err != nil {
+		return nil, fmt.Errorf("reading config %s: %w", path, err)
+	}
+
+	names := make(map[string]bool)
+	for i, job := range c.Jobs {
+		if job.Name == "" {
+			return nil, fmt.Errorf("job %d in %s without name", i+1, path)
+		}
+		if names[job.Name] {
+			return nil, fmt.Errorf("duplicate job %q in %s", job.Name, path)
+		}
+		names[job.Name] = true
+		if _, err := job.Paths(); err != nil {
+			return nil, err
+		}
+	}
+	return &c, nil
+}
+
+// SelectJobs returns the jobs with the given names in the order of the config file, all jobs if names is empty.
+func (x *config) SelectJobs(names []string) ([]configJob, error) {
+	if len(names) == 0 {
+		return x.Jobs, nil
+	}
+	selected := make([]configJob, 0)
+	for _, name := range names {
+		found := false
+		for _, job := range x.Jobs {
+			if job.Name == name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown job %q", name)
+		}
+	}
+	for _, job := range x.Jobs {
+		for _, name := range names {
+			if job.Name == name {
+				selected = append(selected, job)
+				break
+			}
+		}
+	}
+	return selected, nil
+}
+
+// Paths returns the target directories of the job.
+func (x configJob) Paths() ([]string, error) {
+	switch path := x.Path.(type) {
+	case string:
+		if path != "" {
+			return []string{path}, nil
+		}
+	case []any:
+		paths := make([]string, 0, len(path))
+		for _, p := range path {
+			s, ok := p.(string)
+			if !ok || s == "" {
+				return nil, fmt.Errorf("invalid path %v in job %q", p, x.Name)
+			}
+			paths = append(paths, s)
+		}
+		if len(paths) > 0 {
+			return paths, nil
+		}
+	}
+	return nil, fmt.Errorf("job %q without path", x.Name)
+}
+
+// parseEnvJob parses the EnvRoot of a job. Flags set in overrides take precedence over environment variables,
+// which take precedence over the settings of the job and finally the defaults of the config file.
+func parseEnvJob(c *config, job configJob, overrides *pflag.FlagSet) (EnvRoot, error) {
+	cmd := &cobra.Command{
+		Use: job.Name,
+	}
+	addRootFlags(cmd.Flags(), cmd.Flags())
+	flags := cmd.Flags()
+
+	// every layer only sets flags not set by a layer before
+	var err error
+	overrides.Visit(func(flag *pflag.Flag) {
+		if err == nil && flags.Lookup(flag.Name) != nil {
+			err = setFlag(flags, flag.Name, flagValue(flag))
+		}
+	})
+	if err != nil {
+		return EnvRoot{}, err
+	}
+	err = applyEnvironment(flags)
+	if err != nil {
+		return EnvRoot{}, err
+	}
+	for _, settings := range []map[string]any{job.Settings, c.Defaults} {
+		for name, value := range settings {
+			err = setFlag(flags, name, value)
+			if err != nil {
+				return EnvRoot{}, fmt.Errorf("job %q: %w", job.Name, err)
+			}
+		}
+	}
+
+	paths, err := job.Paths()
+	if err != nil {
+		return EnvRoot{}, err
+	}
+	env, err := parseEnvRoot(cmd, paths)
+	if err != nil {
+		return env, fmt.Errorf("job %q: %w", job.Name, err)
+	}
+	env.Job = job.Name
+	return env, nil
+}
+
+// setFlag sets the flag with the given name to value, a scalar or a list, unless it has been set before.
+func setFlag(flags *pflag.FlagSet, name string, value any) error {
+	flag := flags.Lookup(name)
+	if flag == nil {
+		return fmt.Errorf("unknown setting %q", name)
+	}
+	if flag.Changed {
+		return nil
+	}
+
+	switch value := value.(type) {
+	case nil:
+		return fmt.Errorf("setting %q without value", name)
+	case []any:
+		sliceValue, ok := flag.Value.(pflag.SliceValue)
+		if !ok {
+			return fmt.Errorf("setting %q does not take a list", name)
+		}
+		items := make([]string, len(value))
+		for i, item := range value {
+			items[i] = fmt.Sprint(item)
+		}
+		err := sliceValue.Replace(items)
+		if err != nil {
+			return fmt.Errorf("setting %q: %w", name, err)
+		}
+		flag.Changed = true
+		return nil
+	case []string:
+		return setFlag(flags, name, toAnySlice(value))
+	case map[string]any:
+		return fmt.Errorf("setting %q does not take a map", name)
+	default:
+		err := flags.Set(name, fmt.Sprint(value))
+		if err != nil {
+			return fmt.Errorf("setting %q: %w", name, err)
+		}
+		return nil
+	}
+}
+
+// flagValue returns the value of a flag for setFlag.
+func flagValue(flag *pflag.Flag) any {
+	if sliceValue, ok := flag.Value.(pflag.SliceValue); ok {
+		return sliceValue.GetSlice()
+	}
+	return flag.Value.String()
+}
+
+func toAnySlice(values []string) []any {
+	result := make([]any, len(values))
+	for i, value := range values {
+		result[i] = value
+	}
+	return result
+}
+
+// applyEnvironment sets all flags not given on the command line from environment variables, e.g. --dry-run from
+// KEEP_DRY_RUN. List flags take comma-separated values.
+func applyEnvironment(flags *pflag.FlagSet) error {
+	var err error
+	flags.VisitAll(func(flag *pflag.Flag) {
+		if err != nil || flag.Changed {
+			return
+		}
+		name := envName(flag.Name)
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			return
+		}
+		if setErr := flags.Set(flag.Name, value); setErr != nil {
+			err = fmt.Errorf("environment variable %s: %w", name, setErr)
+		}
+	})
+	return err
+}
+
+// envName returns the name of the environment variable for a flag.
+func envName(flag string) string {
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(flag, "-", "_"))
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/jojomi/keep"
+	"github.com/spf13/cobra"
+)
+
+func getConfigCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "work with config files",
+	}
+	cmd.AddCommand(getConfigValidateCmd())
+	return cmd
+}
+
+func getConfigValidateCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "validate [job...]",
+		Short: "check the jobs of a config file without running them",
+		RunE:  runConfigValidate,
+	}
+
+	flags := cmd.Flags()
+	flags.StringP("config", "c", defaultConfigFile, "config file declaring the jobs")
+
+	return cmd
+}
+
+func runConfigValidate(cmd *cobra.Command, args []string) error {
+	cmd.SilenceUsage = true
+	err := applyEnvironment(cmd.Flags())
+	if err != nil {
+		return withExitCode(exitUsage, err)
+	}
+	env, err := parseEnvRun(cmd, args)
+	if err != nil {
+		return withExitCode(exitUsage, err)
+	}
+
+	c, err := loadConfig(env.Config)
+	if err != nil {
+		return withExitCode(exitUsage, err)
+	}
+	jobs, err := c.SelectJobs(env.Jobs)
+	if err != nil {
+		return withExitCode(exitUsage, err)
+	}
+
+	failed := 0
+	for _, job := range jobs {
+		err := validateJob(c, job, cmd)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			failed++
+			continue
+		}
+		fmt.Printf("job %q: ok\n", job.Name)
+	}
+	if failed > 0 {
+		return withExitCode(exitUsage, fmt.Errorf("%d of %d jobs invalid", failed, len(jobs)))
+	}
+	return nil
+}
+
+// validateJob checks that the job can be parsed, has valid requirements, and that its target directories exist.
+func validateJob(c *config, job configJob, cmd *cobra.Command) error {
+	env, err := parseEnvJob(c, job, cmd.Flags())
+	if err != nil {
+		return err
+	}
+
+	reqs, err := parseRequirements(env.Requirements)
+	if err != nil {
+		return fmt.Errorf("job %q: %w", job.Name, err)
+	}
+	err = keep.NewDefaultJailhouse[keep.File]().ValidateRequirements(*reqs)
+	if err != nil {
+		return fmt.Errorf("job %q: %w", job.Name, err)
+	}
+
+	for _, path := range env.Paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return fmt.Errorf("job %q: %w", job.Name, err)
+		}
+		if !info.IsDir() {
+			return fmt.Errorf("job %q: %s is not a directory", job.Name, path)
+		}
+	}
+	return nil
+}
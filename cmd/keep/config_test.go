@@ -0,0 +1,155 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+)
+
+func writeConfig(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "keep.yaml")
+	assert.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	return path
+}
+
+func TestLoadConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr bool
+	}{
+		{
+			name:    "valid",
+			content: "defaults:\n  force: true\njobs:\n  - name: pg\n    path: /backups/pg\n  - name: tar\n    path: [/backups/a, /backups/b]\n",
+		},
+		{
+			name:    "missing name",
+			content: "jobs:\n  - path: /backups/pg\n",
+			wantErr: true,
+		},
+		{
+			name:    "duplicate name",
+			content: "jobs:\n  - name: pg\n    path: /a\n  - name: pg\n    path: /b\n",
+			wantErr: true,
+		},
+		{
+			name:    "missing path",
+			content: "jobs:\n  - name: pg\n",
+			wantErr: true,
+		},
+		{
+			name:    "unknown section",
+			content: "job:\n  - name: pg\n",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := loadConfig(writeConfig(t, tt.content))
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestConfig_SelectJobs(t *testing.T) {
+	c, err := loadConfig(writeConfig(t, "jobs:\n  - name: a\n    path: /a\n  - name: b\n    path: /b\n  - name: c\n    path: /c\n"))
+	assert.NoError(t, err)
+
+	jobs, err := c.SelectJobs(nil)
+	assert.NoError(t, err)
+	assert.Len(t, jobs, 3)
+
+	// the order of the config file wins
+	jobs, err = c.SelectJobs([]string{"c", "a"})
+	assert.NoError(t, err)
+	assert.Equal(t, "a", jobs[0].Name)
+	assert.Equal(t, "c", jobs[1].Name)
+
+	_, err = c.SelectJobs([]string{"d"})
+	assert.Error(t, err)
+}
+
+func TestParseEnvJob(t *testing.T) {
+	c, err := loadConfig(writeConfig(t, `
+defaults:
+  requirements: 7 days
+  force: true
+  unmatched: skip
+  include: ["*.gz"]
+jobs:
+  - name: pg
+    path: [/backups/a, /backups/b]
+    requirements: 14 days
+    dry-run: true
+    exclude: ["tmp-*", "*.part"]
+    include: ["*.sql.gz"]
+  - name: broken
+    path: /backups/c
+    recursive: [true]
+  - name: nameless
+    path: /backups/d
+    time-source: name,mtime
+`))
+	assert.NoError(t, err)
+
+	overrides := pflag.NewFlagSet("run", pflag.ContinueOnError)
+	overrides.Bool("dry-run", false, "")
+	overrides.String("unmatched", "report", "")
+	overrides.String("config", "", "")
+	assert.NoError(t, overrides.Parse([]string{"--dry-run=false", "--config", "x"}))
+	t.Setenv("KEEP_UNMATCHED", "report")
+	t.Setenv("KEEP_REQUIREMENTS", "3 last")
+
+	env, err := parseEnvJob(c, c.Jobs[0], overrides)
+	assert.NoError(t, err)
+	assert.Equal(t, "pg", env.Job)
+	assert.Equal(t, []string{"/backups/a", "/backups/b"}, env.Paths)
+	// command line beats the job
+	assert.False(t, env.DryRun)
+	// environment beats the job and the defaults
+	assert.Equal(t, "3 last", env.Requirements)
+	assert.Equal(t, "report", env.Unmatched)
+	// the job beats the defaults
+	assert.Equal(t, []string{"*.sql.gz"}, env.Filter.Include)
+	assert.Equal(t, []string{"tmp-*", "*.part"}, env.Filter.Exclude)
+	assert.True(t, env.Force)
+
+	_, err = parseEnvJob(c, c.Jobs[1], overrides)
+	assert.Error(t, err)
+
+	// reading times from names needs a pattern
+	_, err = parseEnvJob(c, c.Jobs[2], overrides)
+	assert.ErrorContains(t, err, "time source name requires --time-pattern")
+}
+
+func TestApplyEnvironment(t *testing.T) {
+	flags := pflag.NewFlagSet("keep", pflag.ContinueOnError)
+	flags.Bool("dry-run", false, "")
+	flags.String("anchor", "youngest", "")
+	flags.StringSlice("include", nil, "")
+	assert.NoError(t, flags.Parse([]string{"--anchor", "oldest"}))
+
+	t.Setenv("KEEP_DRY_RUN", "true")
+	t.Setenv("KEEP_ANCHOR", "youngest")
+	t.Setenv("KEEP_INCLUDE", "*.gz,*.xz")
+	assert.NoError(t, applyEnvironment(flags))
+
+	dryRun, _ := flags.GetBool("dry-run")
+	assert.True(t, dryRun)
+	anchor, _ := flags.GetString("anchor")
+	assert.Equal(t, "oldest", anchor)
+	include, _ := flags.GetStringSlice("include")
+	assert.Equal(t, []string{"*.gz", "*.xz"}, include)
+
+	t.Setenv("KEEP_DRY_RUN", "maybe")
+	flags = pflag.NewFlagSet("keep", pflag.ContinueOnError)
+	flags.Bool("dry-run", false, "")
+	assert.Error(t, applyEnvironment(flags))
+}
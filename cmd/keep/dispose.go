@@ -0,0 +1,324 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// disposal gets rid of a freed element at path inside the target directory root. dir is true for directory
+// elements, see --dirs.
+type disposal func(root, path string, dir bool) error
+
+// disposalActions are the actions available for --action, with the verb used to describe them.
+var disposalActions = map[string]string{
+	"delete":     "deleting",
+	"trash":      "trashing",
+	"quarantine": "quarantining",
+	"archive":    "archiving",
+}
+
+// pastTense describes the result of the actions available for --action.
+var pastTense = map[string]string{
+	"delete":     "deleted",
+	"trash":      "trashed",
+	"quarantine": "quarantined",
+	"archive":    "archived",
+}
+
+// newDisposal returns the disposal for the given action. quarantineDir and archiveDir are required for the
+// quarantine and archive actions respectively.
+func newDisposal(action, quarantineDir, archiveDir string) (disposal, error) {
+	switch action {
+	case "delete":
+		return removeElement, nil
+	case "trash":
+		return trashElement, nil
+	case "quarantine":
+		if quarantineDir == "" {
+			return nil, fmt.Errorf("action quarantine requires --quarantine-dir")
+		}
+		return func(root, path string, dir bool) error {
+			return quarantineElement(root, path, quarantineDir)
+		}, nil
+	case "archive":
+		if archiveDir == "" {
+			return nil, fmt.Errorf("action archive requires --archive-dir")
+		}
+		return func(root, path string, dir bool) error {
+			return archiveElement(root, path, dir, archiveDir)
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown action %q, try [delete, trash, quarantine, archive]", action)
+	}
+}
+
+// trashElement moves the element to the freedesktop.org trash of the user, writing the .trashinfo metadata needed
+// to restore it from a file manager.
+func trashElement(root, path string, _ bool) error {
+	err := ensureInside(root, path)
+	if err != nil {
+		return err
+	}
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+
+	trashDir, err := trashDirectory()
+	if err != nil {
+		return err
+	}
+	filesDir := filepath.Join(trashDir, "files")
+	infoDir := filepath.Join(trashDir, "info")
+	for _, dir := range []string{filesDir, infoDir} {
+		err = os.MkdirAll(dir, 0o700)
+		if err != nil {
+			return err
+		}
+	}
+
+	// reserve a unique name by creating its info file exclusively
+	var (
+		name     string
+		infoFile *os.File
+	)
+	for i := 0; infoFile == nil; i++ {
+		name = filepath.Base(absPath)
+		if i > 0 {
+			name += "." + strconv.Itoa(i)
+		}
+		infoFile, err = os.OpenFile(filepath.Join(infoDir, name+".trashinfo"), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+		if err != nil && !errors.Is(err, fs.ErrExist) {
+			return err
+		}
+	}
+	_, err = fmt.Fprintf(infoFile, "[Trash Info]\nPath=%s\nDeletionDate=%s\n", (&url.URL{Path: absPath}).EscapedPath(), time.Now().Format("2006-01-02T15:04:05"))
+	if closeErr := infoFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = moveElement(absPath, filepath.Join(filesDir, name))
+	}
+	if err != nil {
+		_ = os.Remove(filepath.Join(infoDir, name+".trashinfo"))
+		return err
+	}
+	return nil
+}
+
+// trashDirectory returns the home trash directory as defined by the freedesktop.org trash specification.
+func trashDirectory() (string, error) {
+	dataHome := os.Getenv("XDG_DATA_HOME")
+	if dataHome == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		dataHome = filepath.Join(home, ".local", "share")
+	}
+	return filepath.Join(dataHome, "Trash"), nil
+}
+
+// quarantineElement moves the element into quarantineDir, keeping its path relative to root, so that
+// `keep restore` can bring it back.
+func quarantineElement(root, path, quarantineDir string) error {
+	err := ensureInside(root, path)
+	if err != nil {
+		return err
+	}
+	target, err := mirroredPath(root, path, quarantineDir)
+	if err != nil {
+		return err
+	}
+	return moveElement(path, target)
+}
+
+// archiveElement hard-links the element into archiveDir, keeping its path relative to root, and removes it from
+// root afterwards. Directory elements are recreated in the archive with their files hard-linked.
+func archiveElement(root, path string, dir bool, archiveDir string) error {
+	err := ensureInside(root, path)
+	if err != nil {
+		return err
+	}
+	target, err := mirroredPath(root, path, archiveDir)
+	if err != nil {
+		return err
+	}
+	err = linkTree(path, target)
+	if err != nil {
+		return err
+	}
+	return removeElement(root, path, dir)
+}
+
+// mirroredPath returns the path of the element at path relative to root inside targetDir, creating its parent
+// directories. It refuses to overwrite anything already at that path.
+func mirroredPath(root, path, targetDir string) (string, error) {
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return "", err
+	}
+	target := filepath.Join(targetDir, rel)
+	if _, err := os.Lstat(target); err == nil {
+		return "", fmt.Errorf("refusing to overwrite %s", target)
+	}
+	return target, os.MkdirAll(filepath.Dir(target), 0o755)
+}
+
+// moveElement renames source to target, copying regular files if they are on different file systems.
+func moveElement(source, target string) error {
+	err := os.Rename(source, target)
+	if err == nil || !errors.Is(err, syscall.EXDEV) {
+		return err
+	}
+
+	info, statErr := os.Lstat(source)
+	if statErr != nil {
+		return statErr
+	}
+	if !info.Mode().IsRegular() {
+		return fmt.Errorf("cannot move %s to another file system: %w", source, err)
+	}
+	err = copyFile(source, target, info.Mode())
+	if err != nil {
+		return err
+	}
+	return os.Remove(source)
+}
+
+func copyFile(source, target string, mode fs.FileMode) error {
+	in, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_EXCL, mode.Perm())
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(out, in)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(target)
+	}
+	return err
+}
+
+// linkTree hard-links the file at source to target, or recreates the directory tree at source in target with all
+// regular files hard-linked and symbolic links copied as links.
+func linkTree(source, target string) error {
+	return filepath.WalkDir(source, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(source, path)
+		if err != nil {
+			return err
+		}
+		dest := filepath.Join(target, rel)
+
+		switch {
+		case d.IsDir():
+			return os.MkdirAll(dest, 0o755)
+		case d.Type()&fs.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, dest)
+		case d.Type().IsRegular():
+			return os.Link(path, dest)
+		default:
+			return fmt.Errorf("cannot archive %s: unsupported file type", path)
+		}
+	})
+}
+
+// restoreQuarantine moves all elements from quarantineDir back to the same relative paths in root, skipping
+// those whose name or relative path does not match one of the include patterns (if any). Existing files are never
+// overwritten. It returns the paths restored.
+func restoreQuarantine(quarantineDir, root string, include []string) ([]string, error) {
+	restored := make([]string, 0)
+	err := filepath.WalkDir(quarantineDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || path == quarantineDir {
+			return err
+		}
+		rel, err := filepath.Rel(quarantineDir, path)
+		if err != nil {
+			return err
+		}
+		if len(include) > 0 {
+			included, err := matchesAnyGlob(include, filepath.ToSlash(rel))
+			if err != nil {
+				return err
+			}
+			if !included {
+				return nil
+			}
+		}
+		// directories are restored as a whole if they are not present yet (directory elements), otherwise merged
+		target := filepath.Join(root, rel)
+		if d.IsDir() {
+			if _, err := os.Lstat(target); err == nil {
+				return nil
+			}
+		}
+		if _, err := os.Lstat(target); err == nil {
+			return fmt.Errorf("refusing to overwrite %s", target)
+		}
+		err = os.MkdirAll(filepath.Dir(target), 0o755)
+		if err != nil {
+			return err
+		}
+		err = moveElement(path, target)
+		if err != nil {
+			return err
+		}
+		restored = append(restored, target)
+		if d.IsDir() {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	if err != nil {
+		return restored, err
+	}
+
+	// clean up directories left empty in the quarantine
+	return restored, removeEmptyDirs(quarantineDir)
+}
+
+// removeEmptyDirs removes all empty directories below root, deepest first.
+func removeEmptyDirs(root string) error {
+	dirs := make([]string, 0)
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && path != root {
+			dirs = append(dirs, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for i := len(dirs) - 1; i >= 0; i-- {
+		entries, err := os.ReadDir(dirs[i])
+		if err == nil && len(entries) == 0 {
+			_ = os.Remove(dirs[i])
+		}
+	}
+	return nil
+}
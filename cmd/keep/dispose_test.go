@@ -0,0 +1,133 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// backupTree creates root with two files, one of them in a subdirectory.
+func backupTree(t *testing.T) (base, root string) {
+	base = t.TempDir()
+	root = filepath.Join(base, "backups")
+	assert.NoError(t, os.MkdirAll(filepath.Join(root, "2024"), 0o755))
+	assert.NoError(t, os.WriteFile(filepath.Join(root, "a.sql.gz"), []byte("a"), 0o644))
+	assert.NoError(t, os.WriteFile(filepath.Join(root, "2024", "b.sql.gz"), []byte("b"), 0o644))
+	return base, root
+}
+
+func TestNewDisposal(t *testing.T) {
+	tests := []struct {
+		action        string
+		quarantineDir string
+		archiveDir    string
+		wantErr       bool
+	}{
+		{action: "delete"},
+		{action: "trash"},
+		{action: "quarantine", quarantineDir: "q"},
+		{action: "quarantine", wantErr: true},
+		{action: "archive", archiveDir: "a"},
+		{action: "archive", wantErr: true},
+		{action: "shred", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.action, func(t *testing.T) {
+			got, err := newDisposal(tt.action, tt.quarantineDir, tt.archiveDir)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.NotNil(t, got)
+		})
+	}
+}
+
+func TestTrashElement(t *testing.T) {
+	base, root := backupTree(t)
+	t.Setenv("XDG_DATA_HOME", filepath.Join(base, "data"))
+	trash := filepath.Join(base, "data", "Trash")
+
+	// a second element with the same name gets a unique one
+	assert.NoError(t, trashElement(root, filepath.Join(root, "a.sql.gz"), false))
+	assert.NoError(t, os.WriteFile(filepath.Join(root, "a.sql.gz"), []byte("a2"), 0o644))
+	assert.NoError(t, trashElement(root, filepath.Join(root, "a.sql.gz"), false))
+
+	assert.NoFileExists(t, filepath.Join(root, "a.sql.gz"))
+	assert.FileExists(t, filepath.Join(trash, "files", "a.sql.gz"))
+	assert.FileExists(t, filepath.Join(trash, "files", "a.sql.gz.1"))
+
+	info, err := os.ReadFile(filepath.Join(trash, "info", "a.sql.gz.trashinfo"))
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(info), "[Trash Info]\nPath="+filepath.Join(root, "a.sql.gz")+"\nDeletionDate="))
+
+	// elements outside of root are refused
+	assert.Error(t, trashElement(filepath.Join(root, "2024"), filepath.Join(root, "x"), false))
+}
+
+func TestQuarantineAndRestore(t *testing.T) {
+	base, root := backupTree(t)
+	quarantine := filepath.Join(base, "quarantine")
+
+	assert.NoError(t, quarantineElement(root, filepath.Join(root, "a.sql.gz"), quarantine))
+	assert.NoError(t, quarantineElement(root, filepath.Join(root, "2024", "b.sql.gz"), quarantine))
+	assert.NoFileExists(t, filepath.Join(root, "a.sql.gz"))
+	assert.FileExists(t, filepath.Join(quarantine, "2024", "b.sql.gz"))
+
+	// only matching elements are restored
+	restored, err := restoreQuarantine(quarantine, root, []string{"b.*"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{filepath.Join(root, "2024", "b.sql.gz")}, restored)
+	assert.NoDirExists(t, filepath.Join(quarantine, "2024"))
+
+	// existing files are not overwritten
+	assert.NoError(t, os.WriteFile(filepath.Join(root, "a.sql.gz"), []byte("new"), 0o644))
+	_, err = restoreQuarantine(quarantine, root, nil)
+	assert.Error(t, err)
+	content, err := os.ReadFile(filepath.Join(root, "a.sql.gz"))
+	assert.NoError(t, err)
+	assert.Equal(t, "new", string(content))
+}
+
+func TestQuarantineAndRestoreDirs(t *testing.T) {
+	root, _ := snapshotTree(t)
+	quarantine := filepath.Join(t.TempDir(), "quarantine")
+
+	assert.NoError(t, quarantineElement(root, filepath.Join(root, "daily.1"), quarantine))
+	assert.NoDirExists(t, filepath.Join(root, "daily.1"))
+
+	restored, err := restoreQuarantine(quarantine, root, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{filepath.Join(root, "daily.1")}, restored)
+	assert.FileExists(t, filepath.Join(root, "daily.1", "etc", "hosts"))
+}
+
+func TestArchiveElement(t *testing.T) {
+	root, outside := snapshotTree(t)
+	archive := filepath.Join(t.TempDir(), "archive")
+
+	assert.NoError(t, archiveElement(root, filepath.Join(root, "daily.1"), true, archive))
+	assert.NoDirExists(t, filepath.Join(root, "daily.1"))
+	assert.FileExists(t, filepath.Join(archive, "daily.1", "etc", "hosts"))
+	link, err := os.Readlink(filepath.Join(archive, "daily.1", "link"))
+	assert.NoError(t, err)
+	assert.Equal(t, outside, link)
+
+	// a file is hard-linked, so it shares the original inode
+	assert.NoError(t, os.WriteFile(filepath.Join(root, "file"), []byte("x"), 0o644))
+	before, err := os.Stat(filepath.Join(root, "file"))
+	assert.NoError(t, err)
+	assert.NoError(t, archiveElement(root, filepath.Join(root, "file"), false, archive))
+	after, err := os.Stat(filepath.Join(archive, "file"))
+	assert.NoError(t, err)
+	assert.True(t, os.SameFile(before, after))
+
+	// nothing already archived is overwritten
+	assert.NoError(t, os.WriteFile(filepath.Join(root, "file"), []byte("y"), 0o644))
+	assert.Error(t, archiveElement(root, filepath.Join(root, "file"), false, archive))
+	assert.FileExists(t, filepath.Join(root, "file"))
+}
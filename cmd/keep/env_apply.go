@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+type EnvApply struct {
+	Plan              string
+	DryRun            bool
+	Force             bool
+	AuditLog          string
+	Lock              string
+	LockTimeout       time.Duration
+	LockDir           string
+	DetailedExitCodes bool
+	ConfirmThreshold  int
+}
+
+func parseEnvApply(cmd *cobra.Command, args []string) (EnvApply, error) {
+	var err error
+
+	env := EnvApply{
+		Plan: args[0],
+	}
+
+	env.DryRun, err = cmd.Flags().GetBool("dry-run")
+	if err != nil {
+		return env, err
+	}
+
+	env.Force, err = cmd.Flags().GetBool("force")
+	if err != nil {
+		return env, err
+	}
+	yes, err := cmd.Flags().GetBool("yes")
+	if err != nil {
+		return env, err
+	}
+	env.Force = env.Force || yes
+
+	env.ConfirmThreshold, err = cmd.Flags().GetInt("confirm-threshold")
+	if err != nil {
+		return env, err
+	}
+
+	env.AuditLog, err = cmd.Flags().GetString("audit-log")
+	if err != nil {
+		return env, err
+	}
+
+	env.Lock, err = cmd.Flags().GetString("lock")
+	if err != nil {
+		return env, err
+	}
+	if env.Lock != "fail" && env.Lock != "wait" && env.Lock != "off" {
+		return env, fmt.Errorf("unknown value %q for --lock, try [fail, wait, off]", env.Lock)
+	}
+
+	env.LockTimeout, err = cmd.Flags().GetDuration("lock-timeout")
+	if err != nil {
+		return env, err
+	}
+
+	env.LockDir, err = cmd.Flags().GetString("lock-dir")
+	if err != nil {
+		return env, err
+	}
+
+	env.DetailedExitCodes, err = cmd.Flags().GetBool("detailed-exit-codes")
+	if err != nil {
+		return env, err
+	}
+	return env, nil
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+type EnvFilter struct {
+	Requirements  string
+	Anchor        string
+	AnchorEpoch   string
+	Input         string
+	TimeField     string
+	IDField       string
+	TimeLayout    string
+	Location      *time.Location
+	Print         string
+	Print0        bool
+	Unmatched     string
+	ReferenceDate time.Time
+}
+
+func parseEnvFilter(cmd *cobra.Command, _ []string) (EnvFilter, error) {
+	var err error
+
+	env := EnvFilter{}
+
+	env.Requirements, err = cmd.Flags().GetString("requirements")
+	if err != nil {
+		return env, err
+	}
+
+	env.Anchor, err = cmd.Flags().GetString("anchor")
+	if err != nil {
+		return env, err
+	}
+
+	env.AnchorEpoch, err = cmd.Flags().GetString("anchor-epoch")
+	if err != nil {
+		return env, err
+	}
+
+	env.Input, err = cmd.Flags().GetString("input")
+	if err != nil {
+		return env, err
+	}
+	if env.Input != "text" && env.Input != "json" {
+		return env, fmt.Errorf("unknown input format %q, try [text, json]", env.Input)
+	}
+
+	env.TimeField, err = cmd.Flags().GetString("time-field")
+	if err != nil {
+		return env, err
+	}
+
+	env.IDField, err = cmd.Flags().GetString("id-field")
+	if err != nil {
+		return env, err
+	}
+
+	env.TimeLayout, err = cmd.Flags().GetString("time-layout")
+	if err != nil {
+		return env, err
+	}
+
+	location, err := cmd.Flags().GetString("time-location")
+	if err != nil {
+		return env, err
+	}
+	env.Location, err = time.LoadLocation(location)
+	if err != nil {
+		return env, err
+	}
+
+	env.Print, err = cmd.Flags().GetString("print")
+	if err != nil {
+		return env, err
+	}
+	if env.Print != "free" && env.Print != "kept" {
+		return env, fmt.Errorf("unknown value %q for --print, try [free, kept]", env.Print)
+	}
+
+	env.Print0, err = cmd.Flags().GetBool("print0")
+	if err != nil {
+		return env, err
+	}
+
+	env.Unmatched, err = cmd.Flags().GetString("unmatched")
+	if err != nil {
+		return env, err
+	}
+	if env.Unmatched != "skip" && env.Unmatched != "report" {
+		return env, fmt.Errorf("unknown value %q for --unmatched, try [skip, report]", env.Unmatched)
+	}
+
+	referenceDate, err := cmd.Flags().GetString("reference-date")
+	if err != nil {
+		return env, err
+	}
+	env.ReferenceDate = time.Now()
+	if referenceDate != "" {
+		env.ReferenceDate, err = time.Parse(time.RFC3339, referenceDate)
+		if err != nil {
+			return env, err
+		}
+	}
+	return env, nil
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/jojomi/keep"
+	"github.com/spf13/cobra"
+)
+
+type EnvGit struct {
+	Repository            string
+	Refs                  string
+	Match                 []string
+	Unmerged              bool
+	Requirements          string
+	PrintRequirementsOnly bool
+	DryRun                bool
+	Force                 bool
+	ConfirmThreshold      int
+	Anchor                string
+	AnchorEpoch           string
+	TimeExtractor         *keep.TimeExtractor
+	Unmatched             string
+	Verbose               bool
+	Reasons               bool
+	Reports               []reportSpec
+	Print0                bool
+	AuditLog              string
+	Guards                guardLimits
+	DetailedExitCodes     bool
+}
+
+func parseEnvGit(cmd *cobra.Command, args []string) (EnvGit, error) {
+	var err error
+
+	env := EnvGit{
+		Repository: ".",
+	}
+	if len(args) > 0 {
+		env.Repository = args[0]
+	}
+
+	env.Refs, err = cmd.Flags().GetString("refs")
+	if err != nil {
+		return env, err
+	}
+	if env.Refs != "tags" && env.Refs != "branches" {
+		return env, fmt.Errorf("unknown value %q for --refs, try [tags, branches]", env.Refs)
+	}
+
+	env.Match, err = cmd.Flags().GetStringSlice("match")
+	if err != nil {
+		return env, err
+	}
+
+	env.Unmerged, err = cmd.Flags().GetBool("unmerged")
+	if err != nil {
+		return env, err
+	}
+	if env.Unmerged && env.Refs != "branches" {
+		return env, fmt.Errorf("--unmerged only applies to --refs branches")
+	}
+
+	env.Requirements, err = cmd.Flags().GetString("requirements")
+	if err != nil {
+		return env, err
+	}
+
+	env.PrintRequirementsOnly, err = cmd.Flags().GetBool("print-requirements-only")
+	if err != nil {
+		return env, err
+	}
+
+	env.DryRun, err = cmd.Flags().GetBool("dry-run")
+	if err != nil {
+		return env, err
+	}
+
+	env.Force, err = cmd.Flags().GetBool("force")
+	if err != nil {
+		return env, err
+	}
+	yes, err := cmd.Flags().GetBool("yes")
+	if err != nil {
+		return env, err
+	}
+	env.Force = env.Force || yes
+
+	env.ConfirmThreshold, err = cmd.Flags().GetInt("confirm-threshold")
+	if err != nil {
+		return env, err
+	}
+
+	env.Anchor, err = cmd.Flags().GetString("anchor")
+	if err != nil {
+		return env, err
+	}
+
+	env.AnchorEpoch, err = cmd.Flags().GetString("anchor-epoch")
+	if err != nil {
+		return env, err
+	}
+
+	// refs are dated by git or by their names, there are no file times
+	if cmd.Flags().Changed("time-source") {
+		return env, fmt.Errorf("--time-source does not apply to git refs, use --time-pattern to read times from their names")
+	}
+
+	timePattern, err := cmd.Flags().GetString("time-pattern")
+	if err != nil {
+		return env, err
+	}
+	if timePattern != "" {
+		timePatternType, err := cmd.Flags().GetString("time-pattern-type")
+		if err != nil {
+			return env, err
+		}
+		timeLocation, err := cmd.Flags().GetString("time-location")
+		if err != nil {
+			return env, err
+		}
+		env.TimeExtractor, err = newTimeExtractor(timePattern, timePatternType, timeLocation)
+		if err != nil {
+			return env, err
+		}
+	}
+
+	env.Unmatched, err = cmd.Flags().GetString("unmatched")
+	if err != nil {
+		return env, err
+	}
+	if env.Unmatched != "skip" && env.Unmatched != "report" {
+		return env, fmt.Errorf("unknown value %q for --unmatched, try [skip, report]", env.Unmatched)
+	}
+
+	env.Verbose, err = cmd.Flags().GetBool("verbose")
+	if err != nil {
+		return env, err
+	}
+
+	env.Reasons, err = cmd.Flags().GetBool("reasons")
+	if err != nil {
+		return env, err
+	}
+
+	reports, err := cmd.Flags().GetStringSlice("report")
+	if err != nil {
+		return env, err
+	}
+	env.Reports, err = parseReportSpecs(reports)
+	if err != nil {
+		return env, err
+	}
+
+	output, err := cmd.Flags().GetString("output")
+	if err != nil {
+		return env, err
+	}
+	if output != "text" {
+		return env, fmt.Errorf("--output %s does not apply to git refs, only text", output)
+	}
+
+	env.Print0, err = cmd.Flags().GetBool("print0")
+	if err != nil {
+		return env, err
+	}
+
+	env.AuditLog, err = cmd.Flags().GetString("audit-log")
+	if err != nil {
+		return env, err
+	}
+
+	env.Guards, err = parseGuardLimits(cmd.Flags())
+	if err != nil {
+		return env, err
+	}
+
+	env.DetailedExitCodes, err = cmd.Flags().GetBool("detailed-exit-codes")
+	if err != nil {
+		return env, err
+	}
+	return env, nil
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"errors"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+type EnvHorizon struct {
+	Requirements string
+	Anchor       string
+	AnchorEpoch  string
+	Interval     time.Duration
+	Jitter       time.Duration
+}
+
+func parseEnvHorizon(cmd *cobra.Command, _ []string) (EnvHorizon, error) {
+	var err error
+
+	env := EnvHorizon{}
+
+	env.Requirements, err = cmd.Flags().GetString("requirements")
+	if err != nil {
+		return env, err
+	}
+
+	env.Anchor, err = cmd.Flags().GetString("anchor")
+	if err != nil {
+		return env, err
+	}
+
+	env.AnchorEpoch, err = cmd.Flags().GetString("anchor-epoch")
+	if err != nil {
+		return env, err
+	}
+	// without a fixed epoch, the grid moves with the oldest element, so there is no steady state
+	if env.Anchor == "oldest" && env.AnchorEpoch == "" {
+		return env, errors.New("--anchor oldest needs --anchor-epoch to calculate a horizon")
+	}
+
+	env.Interval, err = cmd.Flags().GetDuration("interval")
+	if err != nil {
+		return env, err
+	}
+
+	env.Jitter, err = cmd.Flags().GetDuration("jitter")
+	if err != nil {
+		return env, err
+	}
+	return env, nil
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+type EnvLog struct {
+	AuditLog string
+	Filter   auditFilter
+	Output   string
+}
+
+func parseEnvLog(cmd *cobra.Command, _ []string) (EnvLog, error) {
+	var err error
+
+	env := EnvLog{}
+
+	env.AuditLog, err = cmd.Flags().GetString("audit-log")
+	if err != nil {
+		return env, err
+	}
+	if env.AuditLog == "" {
+		return env, fmt.Errorf("no audit log given, use --audit-log")
+	}
+
+	since, err := cmd.Flags().GetString("since")
+	if err != nil {
+		return env, err
+	}
+	env.Filter.Since, err = parseLogTime(since, false)
+	if err != nil {
+		return env, err
+	}
+
+	until, err := cmd.Flags().GetString("until")
+	if err != nil {
+		return env, err
+	}
+	env.Filter.Until, err = parseLogTime(until, true)
+	if err != nil {
+		return env, err
+	}
+
+	env.Filter.Patterns, err = cmd.Flags().GetStringSlice("name")
+	if err != nil {
+		return env, err
+	}
+
+	env.Filter.FailedOnly, err = cmd.Flags().GetBool("failed")
+	if err != nil {
+		return env, err
+	}
+
+	env.Output, err = cmd.Flags().GetString("output")
+	if err != nil {
+		return env, err
+	}
+	if env.Output != "text" && env.Output != "ndjson" {
+		return env, fmt.Errorf("output format %q is not supported by keep log, try [text, ndjson]", env.Output)
+	}
+	return env, nil
+}
+
+// parseLogTime parses a time in RFC 3339 format or a date like 2024-03-01 in local time. Dates given as end of a
+// range include the whole day.
+func parseLogTime(value string, end bool) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", value, time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q, use a date like 2024-03-01 or RFC 3339", value)
+	}
+	if end {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, nil
+}
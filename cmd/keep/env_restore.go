@@ -0,0 +1,39 @@
+package main
+
+import (
+	"github.com/spf13/cobra"
+)
+
+type EnvRestore struct {
+	QuarantineDir string
+	Target        string
+	Include       []string
+	DryRun        bool
+}
+
+func parseEnvRestore(cmd *cobra.Command, args []string) (EnvRestore, error) {
+	var err error
+
+	env := EnvRestore{
+		Target: ".",
+	}
+	if len(args) > 0 {
+		env.Target = args[0]
+	}
+
+	env.QuarantineDir, err = cmd.Flags().GetString("quarantine-dir")
+	if err != nil {
+		return env, err
+	}
+
+	env.Include, err = cmd.Flags().GetStringSlice("include")
+	if err != nil {
+		return env, err
+	}
+
+	env.DryRun, err = cmd.Flags().GetBool("dry-run")
+	if err != nil {
+		return env, err
+	}
+	return env, nil
+}
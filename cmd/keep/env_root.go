@@ -1,6 +1,12 @@
 package main
 
 import (
+	"fmt"
+	"regexp"
+	"slices"
+	"time"
+
+	"github.com/jojomi/keep"
 	"github.com/spf13/cobra"
 )
 
@@ -9,9 +15,37 @@ type EnvRoot struct {
 	Requirements          string
 	Force                 bool
 	DryRun                bool
+	Anchor                string
+	AnchorEpoch           string
+	Reports               []reportSpec
+	TimeSources           []string
+	TimeExtractor         *keep.TimeExtractor
+	Unmatched             string
+	Verbose               bool
+	Paths                 []string
+	Filter                fileFilter
+	Dirs                  bool
+	Action                string
+	QuarantineDir         string
+	ArchiveDir            string
+	Disposal              disposal
+	Exec                  *execCommand
+	Output                string
+	Print0                bool
+	Reasons               bool
+	Job                   string
+	Lock                  string
+	LockTimeout           time.Duration
+	LockDir               string
+	Guards                guardLimits
+	AuditLog              string
+	DetailedExitCodes     bool
+	ConfirmThreshold      int
+	Review                bool
+	GroupBy               *regexp.Regexp
 }
 
-func parseEnvRoot(cmd *cobra.Command, _ []string) (EnvRoot, error) {
+func parseEnvRoot(cmd *cobra.Command, args []string) (EnvRoot, error) {
 	var err error
 
 	env := EnvRoot{}
@@ -30,10 +64,238 @@ func parseEnvRoot(cmd *cobra.Command, _ []string) (EnvRoot, error) {
 	if err != nil {
 		return env, err
 	}
+	yes, err := cmd.Flags().GetBool("yes")
+	if err != nil {
+		return env, err
+	}
+	env.Force = env.Force || yes
+
+	env.ConfirmThreshold, err = cmd.Flags().GetInt("confirm-threshold")
+	if err != nil {
+		return env, err
+	}
+
+	// keep plan does not remove anything, so there is nothing to review
+	if cmd.Flags().Lookup("review") != nil {
+		env.Review, err = cmd.Flags().GetBool("review")
+		if err != nil {
+			return env, err
+		}
+	}
 
 	env.DryRun, err = cmd.Flags().GetBool("dry-run")
 	if err != nil {
 		return env, err
 	}
+
+	env.Anchor, err = cmd.Flags().GetString("anchor")
+	if err != nil {
+		return env, err
+	}
+
+	env.AnchorEpoch, err = cmd.Flags().GetString("anchor-epoch")
+	if err != nil {
+		return env, err
+	}
+
+	reports, err := cmd.Flags().GetStringSlice("report")
+	if err != nil {
+		return env, err
+	}
+	env.Reports, err = parseReportSpecs(reports)
+	if err != nil {
+		return env, err
+	}
+
+	timeSource, err := cmd.Flags().GetString("time-source")
+	if err != nil {
+		return env, err
+	}
+	env.TimeSources, err = parseTimeSources(timeSource)
+	if err != nil {
+		return env, err
+	}
+
+	timePattern, err := cmd.Flags().GetString("time-pattern")
+	if err != nil {
+		return env, err
+	}
+	if timePattern != "" {
+		timePatternType, err := cmd.Flags().GetString("time-pattern-type")
+		if err != nil {
+			return env, err
+		}
+		timeLocation, err := cmd.Flags().GetString("time-location")
+		if err != nil {
+			return env, err
+		}
+		env.TimeExtractor, err = newTimeExtractor(timePattern, timePatternType, timeLocation)
+		if err != nil {
+			return env, err
+		}
+		// a pattern without explicit time sources means reading times from names only
+		if !cmd.Flags().Changed("time-source") {
+			env.TimeSources = []string{nameTimeSource}
+		}
+	}
+	if env.TimeExtractor == nil && slices.Contains(env.TimeSources, nameTimeSource) {
+		return env, fmt.Errorf("time source %s requires --time-pattern", nameTimeSource)
+	}
+
+	env.Unmatched, err = cmd.Flags().GetString("unmatched")
+	if err != nil {
+		return env, err
+	}
+	if env.Unmatched != "skip" && env.Unmatched != "report" {
+		return env, fmt.Errorf("unknown value %q for --unmatched, try [skip, report]", env.Unmatched)
+	}
+
+	env.Verbose, err = cmd.Flags().GetBool("verbose")
+	if err != nil {
+		return env, err
+	}
+
+	env.Paths = args
+	if len(env.Paths) == 0 {
+		env.Paths = []string{"."}
+	}
+
+	env.Filter.Recursive, err = cmd.Flags().GetBool("recursive")
+	if err != nil {
+		return env, err
+	}
+
+	env.Filter.Include, err = cmd.Flags().GetStringSlice("include")
+	if err != nil {
+		return env, err
+	}
+
+	env.Filter.Exclude, err = cmd.Flags().GetStringSlice("exclude")
+	if err != nil {
+		return env, err
+	}
+
+	env.Filter.Extensions, err = cmd.Flags().GetStringSlice("ext")
+	if err != nil {
+		return env, err
+	}
+
+	minSize, err := cmd.Flags().GetString("min-size")
+	if err != nil {
+		return env, err
+	}
+	env.Filter.MinSize, err = parseSize(minSize)
+	if err != nil {
+		return env, err
+	}
+
+	env.Filter.Hidden, err = cmd.Flags().GetBool("hidden")
+	if err != nil {
+		return env, err
+	}
+
+	env.Output, err = cmd.Flags().GetString("output")
+	if err != nil {
+		return env, err
+	}
+
+	env.Print0, err = cmd.Flags().GetBool("print0")
+	if err != nil {
+		return env, err
+	}
+	if env.Print0 && env.Output != "text" {
+		return env, fmt.Errorf("--print0 cannot be combined with --output %s", env.Output)
+	}
+
+	env.Reasons, err = cmd.Flags().GetBool("reasons")
+	if err != nil {
+		return env, err
+	}
+
+	env.Guards, err = parseGuardLimits(cmd.Flags())
+	if err != nil {
+		return env, err
+	}
+
+	env.AuditLog, err = cmd.Flags().GetString("audit-log")
+	if err != nil {
+		return env, err
+	}
+
+	env.DetailedExitCodes, err = cmd.Flags().GetBool("detailed-exit-codes")
+	if err != nil {
+		return env, err
+	}
+
+	env.Lock, err = cmd.Flags().GetString("lock")
+	if err != nil {
+		return env, err
+	}
+	if env.Lock != "fail" && env.Lock != "wait" && env.Lock != "off" {
+		return env, fmt.Errorf("unknown value %q for --lock, try [fail, wait, off]", env.Lock)
+	}
+
+	env.LockTimeout, err = cmd.Flags().GetDuration("lock-timeout")
+	if err != nil {
+		return env, err
+	}
+
+	env.LockDir, err = cmd.Flags().GetString("lock-dir")
+	if err != nil {
+		return env, err
+	}
+
+	env.Dirs, err = cmd.Flags().GetBool("dirs")
+	if err != nil {
+		return env, err
+	}
+
+	env.Action, err = cmd.Flags().GetString("action")
+	if err != nil {
+		return env, err
+	}
+	env.QuarantineDir, err = cmd.Flags().GetString("quarantine-dir")
+	if err != nil {
+		return env, err
+	}
+	env.ArchiveDir, err = cmd.Flags().GetString("archive-dir")
+	if err != nil {
+		return env, err
+	}
+	groupBy, err := cmd.Flags().GetString("group-by")
+	if err != nil {
+		return env, err
+	}
+	env.GroupBy, err = parseGroupPattern(groupBy)
+	if err != nil {
+		return env, err
+	}
+
+	env.Disposal, err = newDisposal(env.Action, env.QuarantineDir, env.ArchiveDir)
+	if err != nil {
+		return env, err
+	}
+
+	execCmd, err := cmd.Flags().GetString("exec")
+	if err != nil {
+		return env, err
+	}
+	execBatch, err := cmd.Flags().GetString("exec-batch")
+	if err != nil {
+		return env, err
+	}
+	switch {
+	case execCmd != "" && execBatch != "":
+		return env, fmt.Errorf("--exec and --exec-batch are mutually exclusive")
+	case (execCmd != "" || execBatch != "") && cmd.Flags().Changed("action"):
+		return env, fmt.Errorf("--exec and --exec-batch cannot be combined with --action")
+	case execCmd != "":
+		env.Exec, err = parseExecCommand(execCmd, false)
+	case execBatch != "":
+		env.Exec, err = parseExecCommand(execBatch, true)
+	}
+	if err != nil {
+		return env, err
+	}
 	return env, nil
 }
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"github.com/spf13/cobra"
+)
+
+type EnvRun struct {
+	Config            string
+	Jobs              []string
+	DetailedExitCodes bool
+}
+
+func parseEnvRun(cmd *cobra.Command, args []string) (EnvRun, error) {
+	var err error
+
+	env := EnvRun{
+		Jobs: args,
+	}
+
+	env.Config, err = cmd.Flags().GetString("config")
+	if err != nil {
+		return env, err
+	}
+
+	env.DetailedExitCodes, err = cmd.Flags().GetBool("detailed-exit-codes")
+	if err != nil {
+		return env, err
+	}
+	return env, nil
+}
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/jojomi/keep"
+)
+
+// execBatchMaxBytes limits the length of the arguments of a single --exec-batch invocation, well below the limits
+// of common operating systems.
+const execBatchMaxBytes = 128 * 1024
+
+// execPlaceholders are the placeholders available in --exec and --exec-batch commands.
+var execPlaceholders = []string{"{}", "{path}", "{name}", "{time}", "{tags}"}
+
+// execCommand disposes of freed elements by running an external command, see --exec and --exec-batch.
+type execCommand struct {
+	command string
+	args    []string
+	batch   bool
+}
+
+// execInvocation is a single run of an execCommand for some elements.
+type execInvocation struct {
+	args     []string
+	elements []*keep.JailhouseTimeResource[fileElement]
+}
+
+// parseExecCommand parses a command line like `rclone delete remote:{name}`. Batch commands have to end in a
+// separate "+", like with `find -exec`. Every command needs at least one placeholder.
+func parseExecCommand(command string, batch bool) (*execCommand, error) {
+	args, err := splitCommandLine(command)
+	if err != nil {
+		return nil, err
+	}
+	if batch {
+		if len(args) == 0 || args[len(args)-1] != "+" {
+			return nil, fmt.Errorf("batch command %q must end in \"+\"", command)
+		}
+		args = args[:len(args)-1]
+	}
+	if len(args) == 0 {
+		return nil, fmt.Errorf("empty command")
+	}
+	if !hasPlaceholder(args) {
+		return nil, fmt.Errorf("command %q without placeholder, try [%s]", command, strings.Join(execPlaceholders, ", "))
+	}
+	return &execCommand{
+		command: command,
+		args:    args,
+		batch:   batch,
+	}, nil
+}
+
+// invocations returns the commands to run for the given elements: one per element, or as few as possible in batch
+// mode, where every argument containing a placeholder is repeated for every element.
+func (x *execCommand) invocations(elements []*keep.JailhouseTimeResource[fileElement]) []execInvocation {
+	invocations := make([]execInvocation, 0)
+	if !x.batch {
+		for _, element := range elements {
+			args := make([]string, len(x.args))
+			for i, arg := range x.args {
+				args[i] = expandPlaceholders(arg, element)
+			}
+			invocations = append(invocations, execInvocation{
+				args:     args,
+				elements: []*keep.JailhouseTimeResource[fileElement]{element},
+			})
+		}
+		return invocations
+	}
+
+	var current *execInvocation
+	size := 0
+	for _, element := range elements {
+		elementSize := 0
+		for _, arg := range x.args {
+			if hasPlaceholder([]string{arg}) {
+				elementSize += len(expandPlaceholders(arg, element)) + 1
+			}
+		}
+		if current != nil && size+elementSize > execBatchMaxBytes {
+			invocations = append(invocations, *current)
+			current = nil
+		}
+		if current == nil {
+			current = &execInvocation{}
+			size = 0
+		}
+		current.elements = append(current.elements, element)
+		size += elementSize
+	}
+	if current != nil {
+		invocations = append(invocations, *current)
+	}
+
+	// build the arguments of every batch in the order of the command line
+	for i := range invocations {
+		args := make([]string, 0)
+		for _, arg := range x.args {
+			if !hasPlaceholder([]string{arg}) {
+				args = append(args, arg)
+				continue
+			}
+			for _, element := range invocations[i].elements {
+				args = append(args, expandPlaceholders(arg, element))
+			}
+		}
+		invocations[i].args = args
+	}
+	return invocations
+}
+
+// runExec runs the command for the elements, printing the commands instead if dryRun is set. Messages and the
+// output of the commands are written to w, the outcome for every element is passed to onResult (if not nil). It
+// returns an error summarizing all failed invocations.
+func runExec(command *execCommand, elements []*keep.JailhouseTimeResource[fileElement], dryRun bool, w io.Writer, onResult func(*keep.JailhouseTimeResource[fileElement], error)) error {
+	invocations := command.invocations(elements)
+	failures := make([]string, 0)
+	failedElements := 0
+	for _, invocation := range invocations {
+		if dryRun {
+			fmt.Fprintf(w, "[DRY-RUN] Would be running %s\n", formatCommandLine(invocation.args))
+			continue
+		}
+
+		cmd := exec.Command(invocation.args[0], invocation.args[1:]...)
+		cmd.Stdout = w
+		cmd.Stderr = os.Stderr
+		err := cmd.Run()
+		if onResult != nil {
+			for _, element := range invocation.elements {
+				onResult(element, err)
+			}
+		}
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", formatCommandLine(invocation.args), err))
+			failedElements += len(invocation.elements)
+		}
+	}
+	if len(failures) > 0 {
+		return fmt.Errorf("%d of %d commands failed (%d of %d elements):\n%s", len(failures), len(invocations), failedElements, len(elements), strings.Join(failures, "\n"))
+	}
+	return nil
+}
+
+func hasPlaceholder(args []string) bool {
+	for _, arg := range args {
+		for _, placeholder := range execPlaceholders {
+			if strings.Contains(arg, placeholder) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// expandPlaceholders replaces the placeholders in arg by the values of element.
+func expandPlaceholders(arg string, element *keep.JailhouseTimeResource[fileElement]) string {
+	tags := make([]string, len(element.GetTags()))
+	for i, tag := range element.GetTags() {
+		tags[i] = tag.String()
+	}
+	path := element.TimeResource.Filename
+	return strings.NewReplacer(
+		"{}", path,
+		"{path}", path,
+		"{name}", filepath.Base(path),
+		"{time}", element.GetTime().Format(time.RFC3339),
+		"{tags}", strings.Join(tags, ","),
+	).Replace(arg)
+}
+
+// splitCommandLine splits a command line into arguments like a POSIX shell, supporting single quotes, double quotes
+// and backslash escapes outside of single quotes, but no expansions.
+func splitCommandLine(command string) ([]string, error) {
+	var (
+		args    = make([]string, 0)
+		current strings.Builder
+		inArg   bool
+		quote   rune
+		escaped bool
+	)
+	for _, r := range command {
+		switch {
+		case escaped:
+			current.WriteRune(r)
+			escaped = false
+		case quote == '\'':
+			if r == '\'' {
+				quote = 0
+			} else {
+				current.WriteRune(r)
+			}
+		case r == '\\':
+			escaped = true
+			inArg = true
+		case quote == '"':
+			if r == '"' {
+				quote = 0
+			} else {
+				current.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			inArg = true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+		default:
+			current.WriteRune(r)
+			inArg = true
+		}
+	}
+	if escaped || quote != 0 {
+		return nil, fmt.Errorf("unterminated quote or escape in %q", command)
+	}
+	if inArg {
+		args = append(args, current.String())
+	}
+	return args, nil
+}
+
+// formatCommandLine quotes the arguments for printing, so they can be copied to a shell.
+func formatCommandLine(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		if arg != "" && !strings.ContainsAny(arg, " \t\n'\"\\$`*?[]{}()<>|&;#~") {
+			quoted[i] = arg
+			continue
+		}
+		quoted[i] = "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+	}
+	return strings.Join(quoted, " ")
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jojomi/keep"
+	"github.com/stretchr/testify/assert"
+)
+
+func execElements(paths ...string) []*keep.JailhouseTimeResource[fileElement] {
+	elements := make([]*keep.JailhouseTimeResource[fileElement], len(paths))
+	for i, path := range paths {
+		elements[i] = keep.NewJailhouseTimeResource(fileElement{
+			Filename: path,
+			Time:     time.Date(2024, 3, 1+i, 2, 0, 0, 0, time.UTC),
+		})
+	}
+	return elements
+}
+
+func TestSplitCommandLine(t *testing.T) {
+	tests := []struct {
+		command string
+		want    []string
+		wantErr bool
+	}{
+		{command: "rm -- {}", want: []string{"rm", "--", "{}"}},
+		{command: "  echo   'a b'  \"c d\" e\\ f ", want: []string{"echo", "a b", "c d", "e f"}},
+		{command: `echo 'it'\''s' "say \"hi\"" ''`, want: []string{"echo", "it's", `say "hi"`, ""}},
+		{command: "", want: []string{}},
+		{command: "echo 'open", wantErr: true},
+		{command: `echo \`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.command, func(t *testing.T) {
+			got, err := splitCommandLine(tt.command)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, got, mustSplit(t, formatCommandLine(got)))
+		})
+	}
+}
+
+func mustSplit(t *testing.T, command string) []string {
+	args, err := splitCommandLine(command)
+	assert.NoError(t, err)
+	return args
+}
+
+func TestParseExecCommand(t *testing.T) {
+	tests := []struct {
+		command string
+		batch   bool
+		wantErr bool
+	}{
+		{command: "rm {}"},
+		{command: "rm {} +", batch: true},
+		{command: "rm", wantErr: true},
+		{command: "rm {}", batch: true, wantErr: true},
+		{command: "+", batch: true, wantErr: true},
+		{command: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.command, func(t *testing.T) {
+			_, err := parseExecCommand(tt.command, tt.batch)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestExecCommand_Invocations(t *testing.T) {
+	elements := execElements("db/a.sql.gz", "db/b.sql.gz")
+	elements[0].AddTag(keep.TimeRangeTag{TimeRange: keep.DAY, Index: 0})
+
+	command, err := parseExecCommand("upload --name {name} --at={time} --tags={tags} {}", false)
+	assert.NoError(t, err)
+	invocations := command.invocations(elements)
+	assert.Len(t, invocations, 2)
+	assert.Equal(t, []string{"upload", "--name", "a.sql.gz", "--at=2024-03-01T02:00:00Z", "--tags=" + elements[0].GetTags()[0].String(), "db/a.sql.gz"}, invocations[0].args)
+	assert.Equal(t, []string{"upload", "--name", "b.sql.gz", "--at=2024-03-02T02:00:00Z", "--tags=", "db/b.sql.gz"}, invocations[1].args)
+
+	command, err = parseExecCommand("rm -- {path} +", true)
+	assert.NoError(t, err)
+	invocations = command.invocations(elements)
+	assert.Len(t, invocations, 1)
+	assert.Equal(t, []string{"rm", "--", "db/a.sql.gz", "db/b.sql.gz"}, invocations[0].args)
+
+	// large batches are split
+	paths := make([]string, 0)
+	for i := 0; i < 3; i++ {
+		paths = append(paths, strings.Repeat("x", execBatchMaxBytes/2))
+	}
+	invocations = command.invocations(execElements(paths...))
+	assert.Len(t, invocations, 3)
+}
+
+func TestRunExec(t *testing.T) {
+	dir := t.TempDir()
+	paths := []string{filepath.Join(dir, "a"), filepath.Join(dir, "b")}
+	for _, path := range paths {
+		assert.NoError(t, os.WriteFile(path, []byte("x"), 0o644))
+	}
+
+	command, err := parseExecCommand("rm -- {} +", true)
+	assert.NoError(t, err)
+	assert.NoError(t, runExec(command, execElements(paths...), true, io.Discard, nil))
+	assert.FileExists(t, paths[0])
+
+	assert.NoError(t, runExec(command, execElements(paths...), false, io.Discard, nil))
+	assert.NoFileExists(t, paths[0])
+	assert.NoFileExists(t, paths[1])
+
+	// failures are collected for all elements
+	command, err = parseExecCommand("rm -- {}", false)
+	assert.NoError(t, err)
+	err = runExec(command, execElements(paths...), false, io.Discard, nil)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "2 of 2 commands failed")
+}
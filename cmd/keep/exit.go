@@ -0,0 +1,62 @@
+package main
+
+import "errors"
+
+// Exit codes of keep, see the README. They are part of the interface for monitoring, so never change them.
+const (
+	// exitOK means all free elements were removed (or there were none, see exitNothingRemoved).
+	exitOK = 0
+	// exitError is used for all errors without a more specific code, e.g. failing to write the output.
+	exitError = 1
+	// exitUsage means invalid flags, requirements, or config files. Nothing has been removed.
+	exitUsage = 2
+	// exitLocked means a target is locked by another process. Nothing has been removed from it.
+	exitLocked = 3
+	// exitPartialFailure means some free elements could not be removed, the others were.
+	exitPartialFailure = 4
+	// exitGuard means a safety guard fired. Nothing has been removed from the target.
+	exitGuard = 5
+	// exitScan means a target could not be read. Nothing has been removed from it.
+	exitScan = 6
+	// exitAborted means the user declined the confirmation. Nothing has been removed.
+	exitAborted = 7
+	// exitNothingRemoved means there were no free elements, only used with --detailed-exit-codes.
+	exitNothingRemoved = 8
+)
+
+// exitCodeError attaches an exit code to an error.
+type exitCodeError struct {
+	code int
+	err  error
+}
+
+func (x *exitCodeError) Error() string {
+	return x.err.Error()
+}
+
+func (x *exitCodeError) Unwrap() error {
+	return x.err
+}
+
+// withExitCode makes keep exit with code if err ends the command. Codes already attached to err are replaced.
+func withExitCode(code int, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &exitCodeError{
+		code: code,
+		err:  err,
+	}
+}
+
+// exitCode returns the exit code attached to err by withExitCode, exitError if there is none, and exitOK for nil.
+func exitCode(err error) int {
+	if err == nil {
+		return exitOK
+	}
+	var codeErr *exitCodeError
+	if errors.As(err, &codeErr) {
+		return codeErr.code
+	}
+	return exitError
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{name: "nil", err: nil, want: exitOK},
+		{name: "plain", err: errors.New("broken"), want: exitError},
+		{name: "attached", err: withExitCode(exitGuard, errors.New("guard")), want: exitGuard},
+		{name: "wrapped", err: fmt.Errorf("target: %w", withExitCode(exitLocked, errors.New("locked"))), want: exitLocked},
+		{name: "replaced", err: withExitCode(exitPartialFailure, withExitCode(exitUsage, errors.New("x"))), want: exitPartialFailure},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, exitCode(tt.err))
+		})
+	}
+	assert.Nil(t, withExitCode(exitUsage, nil))
+}
+
+func TestRunEnvRoot_ExitCodes(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"pg-2024-03-01.sql", "pg-2024-03-02.sql", "pg-2024-03-03.sql", "pg-2024-03-04.sql"} {
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(name), 0o644))
+	}
+	c, err := loadConfig(writeConfig(t, `
+jobs:
+  - name: pg
+    path: `+dir+`
+    requirements: 1 last
+    time-pattern: pg-2006-01-02
+    force: true
+    lock: "off"
+`))
+	assert.NoError(t, err)
+	env, err := parseEnvJob(c, c.Jobs[0], pflag.NewFlagSet("run", pflag.ContinueOnError))
+	assert.NoError(t, err)
+
+	// failing elements do not stop the others
+	dispose := env.Disposal
+	env.Disposal = func(root, path string, dir bool) error {
+		if filepath.Base(path) == "pg-2024-03-02.sql" {
+			return errors.New("permission denied")
+		}
+		return dispose(root, path, dir)
+	}
+	removed, err := runEnvRoot(env)
+	assert.Equal(t, exitPartialFailure, exitCode(err))
+	assert.ErrorContains(t, err, "failed to remove 1 of 3 files")
+	assert.Equal(t, 2, removed)
+	assert.NoFileExists(t, filepath.Join(dir, "pg-2024-03-01.sql"))
+	assert.FileExists(t, filepath.Join(dir, "pg-2024-03-02.sql"))
+	assert.NoFileExists(t, filepath.Join(dir, "pg-2024-03-03.sql"))
+
+	// nothing to remove
+	env.Requirements = "10 last"
+	removed, err = runEnvRoot(env)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, removed)
+
+	// invalid requirements
+	env.Requirements = "70000 days"
+	_, err = runEnvRoot(env)
+	assert.Equal(t, exitUsage, exitCode(err))
+	env.Requirements = "7 fortnights"
+	_, err = runEnvRoot(env)
+	assert.Equal(t, exitUsage, exitCode(err))
+	env.Requirements = ""
+	_, err = runEnvRoot(env)
+	assert.Equal(t, exitUsage, exitCode(err))
+	assert.FileExists(t, filepath.Join(dir, "pg-2024-03-04.sql"))
+
+	// unreadable target
+	env.Requirements = "1 last"
+	env.Paths = []string{filepath.Join(dir, "missing")}
+	_, err = runEnvRoot(env)
+	assert.Equal(t, exitScan, exitCode(err))
+}
+
+func TestRunRestore_ExitCodes(t *testing.T) {
+	cmd := getRestoreCmd()
+	cmd.Flags().Bool("dry-run", true, "")
+	assert.NoError(t, cmd.Flags().Set("quarantine-dir", filepath.Join(t.TempDir(), "missing")))
+	err := runRestore(cmd, []string{t.TempDir()})
+	assert.Equal(t, exitScan, exitCode(err))
+
+	assert.NoError(t, cmd.Flags().Set("dry-run", "false"))
+	err = runRestore(cmd, []string{t.TempDir()})
+	assert.Equal(t, exitPartialFailure, exitCode(err))
+	// settings from the environment
+	cmd = getRestoreCmd()
+	cmd.Flags().Bool("dry-run", false, "")
+	t.Setenv("KEEP_DRY_RUN", "true")
+	t.Setenv("KEEP_QUARANTINE_DIR", filepath.Join(t.TempDir(), "missing"))
+	err = runRestore(cmd, []string{t.TempDir()})
+	assert.Equal(t, exitScan, exitCode(err))
+
+	t.Setenv("KEEP_DRY_RUN", "maybe")
+	cmd = getRestoreCmd()
+	cmd.Flags().Bool("dry-run", false, "")
+	err = runRestore(cmd, []string{t.TempDir()})
+	assert.ErrorContains(t, err, "KEEP_DRY_RUN")
+	assert.Equal(t, exitUsage, exitCode(err))
+}
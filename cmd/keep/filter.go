@@ -0,0 +1,201 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// filterElement is an element read by `keep filter`, identified by an arbitrary string.
+type filterElement struct {
+	ID   string
+	Time time.Time
+	// Line is the input line, which is written for JSON input.
+	Line string
+}
+
+func (x filterElement) GetTime() time.Time {
+	return x.Time
+}
+
+// TieBreakKey orders elements with the same time by their identifier.
+func (x filterElement) TieBreakKey() string {
+	return x.ID
+}
+
+func (x filterElement) String() string {
+	return x.ID
+}
+
+func getFilterCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "filter",
+		Short: "read elements from stdin and print the free (or kept) ones, without removing anything",
+		Long: `Reads one element per line from stdin, either as text "<time> <identifier>" or as JSON object, applies the
+requirements, and prints the identifiers of the free (or kept) elements. For JSON input the lines are printed as
+they were read.`,
+		Args: cobra.NoArgs,
+		RunE: runFilter,
+	}
+
+	flags := cmd.Flags()
+	flags.String("input", "text", "input format: text (time and identifier separated by whitespace) or json (one object per line)")
+	flags.String("time-layout", time.RFC3339, "Go time layout of the times, or unix for seconds since the epoch")
+	flags.String("time-field", "time", "field holding the time for --input json")
+	flags.String("id-field", "", "field holding the identifier for --input json, used for ordering elements with the same time")
+	flags.String("print", "free", "which elements to print: free or kept")
+	flags.String("reference-date", "", "evaluate for this time (RFC 3339) instead of now")
+
+	return cmd
+}
+
+func runFilter(cmd *cobra.Command, args []string) error {
+	cmd.SilenceUsage = true
+	err := applyEnvironment(cmd.Flags())
+	if err != nil {
+		return withExitCode(exitUsage, err)
+	}
+	env, err := parseEnvFilter(cmd, args)
+	if err != nil {
+		return withExitCode(exitUsage, err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	return filterStream(ctx, env, stdin, os.Stdout, os.Stderr)
+}
+
+// filterStream reads elements from r, applies the requirements of env, and writes the selected elements to w.
+// Lines that cannot be parsed are reported to errOut with --unmatched report. Errors carry their exit code, an
+// interruption via ctx exitAborted.
+func filterStream(ctx context.Context, env EnvFilter, r io.Reader, w, errOut io.Writer) error {
+	reqs, err := parseRequirements(env.Requirements)
+	if err != nil {
+		return err
+	}
+	jh, err := newJailhouse[filterElement](env.Anchor, env.AnchorEpoch)
+	if err != nil {
+		return err
+	}
+
+	elements := make([]filterElement, 0)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimRight(scanner.Text(), "\r")
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+		element, err := parseFilterLine(env, text)
+		if err != nil {
+			if env.Unmatched == "report" {
+				fmt.Fprintf(errOut, "Skipping line %d: %v\n", line, err)
+			}
+			continue
+		}
+		elements = append(elements, element)
+	}
+	if err := scanner.Err(); err != nil {
+		return withExitCode(exitScan, err)
+	}
+
+	jh.AddElements(elements...)
+	err = jh.ApplyRequirementsForDateContext(ctx, *reqs, env.ReferenceDate)
+	if errors.Is(err, context.Canceled) {
+		return withExitCode(exitAborted, err)
+	}
+	if err != nil {
+		return withExitCode(exitUsage, err)
+	}
+
+	selected := jh.FreeElements()
+	if env.Print == "kept" {
+		selected = jh.KeptElements()
+	}
+	terminator := "\n"
+	if env.Print0 {
+		terminator = "\x00"
+	}
+	buffered := bufio.NewWriter(w)
+	for _, element := range selected {
+		output := element.TimeResource.ID
+		if env.Input == "json" {
+			output = element.TimeResource.Line
+		}
+		if _, err := buffered.WriteString(output + terminator); err != nil {
+			return err
+		}
+	}
+	return buffered.Flush()
+}
+
+// parseFilterLine parses a single input line of `keep filter`.
+func parseFilterLine(env EnvFilter, line string) (filterElement, error) {
+	if env.Input == "json" {
+		var object map[string]any
+		decoder := json.NewDecoder(strings.NewReader(line))
+		decoder.UseNumber()
+		err := decoder.Decode(&object)
+		if err != nil {
+			return filterElement{}, err
+		}
+		value, ok := object[env.TimeField]
+		if !ok {
+			return filterElement{}, fmt.Errorf("missing field %q", env.TimeField)
+		}
+		t, err := parseFilterTime(env, fmt.Sprint(value))
+		if err != nil {
+			return filterElement{}, err
+		}
+		id := line
+		if env.IDField != "" {
+			value, ok := object[env.IDField]
+			if !ok {
+				return filterElement{}, fmt.Errorf("missing field %q", env.IDField)
+			}
+			id = fmt.Sprint(value)
+		}
+		return filterElement{
+			ID:   id,
+			Time: t,
+			Line: line,
+		}, nil
+	}
+
+	fields := strings.Fields(line)
+	if len(fields) < 2 {
+		return filterElement{}, fmt.Errorf("expected time and identifier in %q", line)
+	}
+	t, err := parseFilterTime(env, fields[0])
+	if err != nil {
+		return filterElement{}, err
+	}
+	// the identifier is the rest of the line, so it may contain whitespace
+	id := strings.TrimSpace(strings.TrimLeft(line, " \t")[len(fields[0]):])
+	return filterElement{
+		ID:   id,
+		Time: t,
+		Line: line,
+	}, nil
+}
+
+func parseFilterTime(env EnvFilter, value string) (time.Time, error) {
+	if env.TimeLayout == "unix" {
+		seconds, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid unix time %q", value)
+		}
+		return time.Unix(0, int64(seconds*float64(time.Second))).In(env.Location), nil
+	}
+	return time.ParseInLocation(env.TimeLayout, value, env.Location)
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func filterEnv(input string) EnvFilter {
+	return EnvFilter{
+		Requirements:  "2 last",
+		Anchor:        "youngest",
+		Input:         input,
+		TimeField:     "time",
+		TimeLayout:    time.RFC3339,
+		Location:      time.UTC,
+		Print:         "free",
+		Unmatched:     "report",
+		ReferenceDate: time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC),
+	}
+}
+
+func TestFilterStream(t *testing.T) {
+	textInput := `2024-03-01T02:00:00Z s3://bucket/db 1.sql
+2024-03-03T02:00:00Z s3://bucket/db-3.sql
+
+2024-03-02T02:00:00Z s3://bucket/db-2.sql
+2024-03-05T02:00:00Z
+2024-03-04T02:00:00Z s3://bucket/db-4.sql
+`
+	jsonInput := `{"key": "db-1", "time": "2024-03-01T02:00:00Z"}
+{"key": "db-3", "time": "2024-03-03T02:00:00Z"}
+{"key": "db-2", "time": "2024-03-02T02:00:00Z"}
+{"key": "db-4"}
+{"key": "db-5", "time": "2024-03-05T02:00:00Z"}
+`
+	unixInput := `{"id": 1, "created": 1709258400}
+{"id": 2, "created": 1709344800.5}
+{"id": 3, "created": 1709431200}
+`
+
+	tests := []struct {
+		name       string
+		env        func(env EnvFilter) EnvFilter
+		input      string
+		want       string
+		wantErrOut string
+	}{
+		{
+			name:       "text free",
+			env:        func(env EnvFilter) EnvFilter { return env },
+			input:      textInput,
+			want:       "s3://bucket/db-2.sql\ns3://bucket/db 1.sql\n",
+			wantErrOut: "Skipping line 5: expected time and identifier in \"2024-03-05T02:00:00Z\"\n",
+		},
+		{
+			name: "text kept, NUL separated, skipping silently",
+			env: func(env EnvFilter) EnvFilter {
+				env.Print, env.Print0, env.Unmatched = "kept", true, "skip"
+				return env
+			},
+			input: textInput,
+			want:  "s3://bucket/db-4.sql\x00s3://bucket/db-3.sql\x00",
+		},
+		{
+			name: "json",
+			env: func(env EnvFilter) EnvFilter {
+				env.Input, env.IDField = "json", "key"
+				return env
+			},
+			input:      jsonInput,
+			want:       "{\"key\": \"db-2\", \"time\": \"2024-03-02T02:00:00Z\"}\n{\"key\": \"db-1\", \"time\": \"2024-03-01T02:00:00Z\"}\n",
+			wantErrOut: "Skipping line 4: missing field \"time\"\n",
+		},
+		{
+			name: "json unix times",
+			env: func(env EnvFilter) EnvFilter {
+				env.Input, env.TimeField, env.TimeLayout, env.Print = "json", "created", "unix", "kept"
+				return env
+			},
+			input: unixInput,
+			want:  "{\"id\": 3, \"created\": 1709431200}\n{\"id\": 2, \"created\": 1709344800.5}\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out, errOut bytes.Buffer
+			err := filterStream(context.Background(), tt.env(filterEnv("text")), strings.NewReader(tt.input), &out, &errOut)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, out.String())
+			assert.Equal(t, tt.wantErrOut, errOut.String())
+		})
+	}
+}
+
+func TestFilterStream_InvalidRequirements(t *testing.T) {
+	for _, requirements := range []string{"70000 days", "7 fortnights", ""} {
+		env := filterEnv("text")
+		env.Requirements = requirements
+		err := filterStream(context.Background(), env, strings.NewReader(""), &bytes.Buffer{}, &bytes.Buffer{})
+		assert.Error(t, err, requirements)
+		assert.Equal(t, exitUsage, exitCode(err), requirements)
+	}
+}
+
+func TestFilterStream_AnchorEpoch(t *testing.T) {
+	input := ""
+	for day := 1; day <= 9; day++ {
+		input += fmt.Sprintf("2024-03-%02dT02:00:00Z db-%d\n", day, day)
+	}
+
+	tests := []struct {
+		name  string
+		epoch string
+		want  string
+	}{
+		{name: "grid starting at the oldest element", epoch: "", want: "db-8\n"},
+		{name: "grid starting at the epoch", epoch: "2024-03-04", want: "db-4\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := filterEnv("text")
+			env.Requirements, env.Anchor, env.AnchorEpoch, env.Print = "1 weeks", "oldest", tt.epoch, "kept"
+			var out bytes.Buffer
+			err := filterStream(context.Background(), env, strings.NewReader(input), &out, &bytes.Buffer{})
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, out.String())
+		})
+	}
+
+	env := filterEnv("text")
+	env.Anchor, env.AnchorEpoch = "oldest", "March"
+	err := filterStream(context.Background(), env, strings.NewReader(input), &bytes.Buffer{}, &bytes.Buffer{})
+	assert.Equal(t, exitUsage, exitCode(err))
+}
+
+func TestFilterStream_Interrupted(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := filterStream(ctx, filterEnv("text"), strings.NewReader("2024-03-01T00:00:00Z a\n"), &bytes.Buffer{}, &bytes.Buffer{})
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, exitAborted, exitCode(err))
+}
@@ -0,0 +1,304 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// gitRefKinds maps the values of --refs to the namespace of their refs and the name of a single ref.
+var gitRefKinds = map[string]struct {
+	prefix   string
+	singular string
+}{
+	"tags":     {"refs/tags/", "tag"},
+	"branches": {"refs/heads/", "branch"},
+}
+
+// gitRef is a tag or local branch of a git repository.
+type gitRef struct {
+	// Name is the name of the ref without its namespace, e.g. nightly-2024-03-01.
+	Name string
+	// Time is the tagger date of annotated tags, the committer date of the referenced commit otherwise.
+	Time time.Time
+}
+
+func (x gitRef) GetTime() time.Time {
+	return x.Time
+}
+
+// TieBreakKey orders refs with the same time by their name.
+func (x gitRef) TieBreakKey() string {
+	return x.Name
+}
+
+func (x gitRef) String() string {
+	return x.Name
+}
+
+func getGitCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "git [repository]",
+		Short: "prune tags or local branches of a git repository by their date",
+		Args:  cobra.MaximumNArgs(1),
+		RunE:  runGit,
+	}
+
+	flags := cmd.Flags()
+	flags.String("refs", "tags", "refs to prune: tags, or branches (local branches without upstream, except the current one)")
+	flags.StringSlice("match", nil, "only consider refs whose name matches one of these glob patterns (e.g. nightly-*)")
+	flags.Bool("unmerged", false, "also delete branches not merged into HEAD (git branch -D)")
+	addGuardFlags(flags)
+
+	return cmd
+}
+
+func runGit(cmd *cobra.Command, args []string) error {
+	cmd.SilenceUsage = true
+	err := applyEnvironment(cmd.Flags())
+	if err != nil {
+		return withExitCode(exitUsage, err)
+	}
+	env, err := parseEnvGit(cmd, args)
+	if err != nil {
+		return withExitCode(exitUsage, err)
+	}
+
+	// with --print0, stdout only gets the names of the free refs
+	info := io.Writer(os.Stdout)
+	if env.Print0 {
+		info = os.Stderr
+	}
+
+	now := time.Now()
+	reqs, err := parseRequirements(env.Requirements)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(info, reqs)
+	if env.PrintRequirementsOnly {
+		return nil
+	}
+
+	refs, err := listGitRefs(env.Repository, env.Refs, env.Match)
+	if err != nil {
+		return withExitCode(exitScan, err)
+	}
+
+	jh, err := newJailhouse[gitRef](env.Anchor, env.AnchorEpoch)
+	if err != nil {
+		return err
+	}
+	var reasons *reasonRecorder[gitRef]
+	if env.Reasons {
+		reasons = newReasonRecorder(jh, now)
+	}
+	elements := make([]gitRef, 0, len(refs))
+	for _, ref := range refs {
+		if env.TimeExtractor != nil {
+			ref.Time, err = env.TimeExtractor.Extract(ref.Name)
+			if err != nil {
+				if env.Unmatched == "report" {
+					fmt.Fprintf(os.Stderr, "Skipping %s: %v\n", ref.Name, err)
+				}
+				continue
+			}
+		}
+		if env.Verbose {
+			fmt.Fprintf(info, "%s: %s\n", ref.Name, ref.Time.Format(time.RFC3339))
+		}
+		elements = append(elements, ref)
+	}
+	jh.AddElements(elements...)
+	err = applyRequirements(jh, reqs, now)
+	if err != nil {
+		return err
+	}
+
+	k := jh.KeptElements()
+	fmt.Fprintf(info, "\nKeeping %d %s:\n", len(k), env.Refs)
+	for _, element := range k {
+		tags := make([]string, len(element.GetTags()))
+		for i, tag := range element.GetTags() {
+			tags[i] = tag.String()
+		}
+		fmt.Fprintf(info, "%s [%s]\n", element.TimeResource.Name, strings.Join(tags, ", "))
+	}
+
+	err = writeReports(jh, env.Reports, info)
+	if err != nil {
+		return err
+	}
+	err = guardAbort(checkGuards(env.Guards, jh.Elements(), jh.GetAnchor(), now))
+	if err != nil {
+		return err
+	}
+
+	r := jh.FreeElements()
+	if env.Print0 {
+		for _, element := range r {
+			fmt.Print(element.TimeResource.Name, "\x00")
+		}
+		return nil
+	}
+
+	if len(r) == 0 {
+		if env.DetailedExitCodes {
+			return withExitCode(exitNothingRemoved, errNothingRemoved)
+		}
+		return nil
+	}
+	fmt.Fprintf(info, "\nRemoving %d %s:\n", len(r), env.Refs)
+	printRemovals(info, r, reasons)
+
+	if !env.Force && !env.DryRun {
+		err = confirmRemoval(info, len(r), env.ConfirmThreshold)
+		if err != nil {
+			return err
+		}
+	}
+
+	var audit *auditLog
+	if !env.DryRun {
+		audit, err = startAuditLog(env.AuditLog, EnvRoot{Action: "delete"}, env.Repository, *reqs, now)
+		if err != nil {
+			return err
+		}
+		defer audit.Close()
+	}
+
+	// keep going on failures, so one broken ref does not hold back the others
+	failures := make([]string, 0)
+	for _, element := range r {
+		name := element.TimeResource.Name
+		if env.DryRun {
+			fmt.Fprintf(info, "[DRY-RUN] Would be deleting %s %s...\n", gitRefKinds[env.Refs].singular, name)
+			continue
+		}
+		err = deleteGitRef(env.Repository, env.Refs, name, env.Unmerged)
+		if auditErr := audit.RemovedRef(element, gitRefKinds[env.Refs].prefix+name, err); auditErr != nil {
+			fmt.Fprintln(os.Stderr, auditErr)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			failures = append(failures, name)
+		}
+	}
+	if env.DryRun {
+		return nil
+	}
+	fmt.Fprintf(info, "deleted %d %s\n", len(r)-len(failures), env.Refs)
+	if len(failures) > 0 {
+		return withExitCode(exitPartialFailure, fmt.Errorf("failed to delete %d of %d %s:\n%s", len(failures), len(r), env.Refs, strings.Join(failures, "\n")))
+	}
+	return nil
+}
+
+// listGitRefs returns the tags or branches of the repository whose names match one of the glob patterns, all of them
+// if there are no patterns. Branches with an upstream and the current branch are left out.
+func listGitRefs(repository, kind string, patterns []string) ([]gitRef, error) {
+	prefix := gitRefKinds[kind].prefix
+	output, err := gitOutput(repository, "for-each-ref", "--format=%(refname)%00%(taggerdate:iso-strict)%00%(committerdate:iso-strict)%00%(*committerdate:iso-strict)%00%(upstream)%00%(HEAD)", strings.TrimSuffix(prefix, "/"))
+	if err != nil {
+		return nil, err
+	}
+
+	refs := make([]gitRef, 0)
+	for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+		if line == "" {
+			continue
+		}
+		fields := strings.Split(line, "\x00")
+		if len(fields) != 6 {
+			return nil, fmt.Errorf("unexpected output of git for-each-ref: %q", line)
+		}
+		refName, taggerDate, committerDate, targetDate, upstream, head := fields[0], fields[1], fields[2], fields[3], fields[4], fields[5]
+		if upstream != "" || head == "*" {
+			continue
+		}
+
+		ref := gitRef{
+			Name: strings.TrimPrefix(refName, prefix),
+		}
+		ok, err := matchesAnyPattern(patterns, ref.Name)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+
+		// annotated tags have a tagger date, lightweight ones point to the commit directly
+		date := taggerDate
+		if date == "" {
+			date = committerDate
+		}
+		if date == "" {
+			date = targetDate
+		}
+		ref.Time, err = time.Parse(time.RFC3339, date)
+		if err != nil {
+			return nil, fmt.Errorf("reading date of %s: %w", refName, err)
+		}
+		refs = append(refs, ref)
+	}
+	return refs, nil
+}
+
+// matchesAnyPattern reports whether name matches one of the glob patterns, true if there are none.
+func matchesAnyPattern(patterns []string, name string) (bool, error) {
+	if len(patterns) == 0 {
+		return true, nil
+	}
+	for _, pattern := range patterns {
+		ok, err := path.Match(pattern, name)
+		if err != nil {
+			return false, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+		if ok {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// deleteGitRef deletes a tag or branch using the git CLI. Branches not merged into HEAD are only deleted if
+// unmerged is set.
+func deleteGitRef(repository, kind, name string, unmerged bool) error {
+	var args []string
+	switch {
+	case kind == "tags":
+		args = []string{"tag", "--delete", name}
+	case unmerged:
+		args = []string{"branch", "-D", name}
+	default:
+		args = []string{"branch", "--delete", name}
+	}
+	_, err := gitOutput(repository, args...)
+	return err
+}
+
+// gitOutput runs git with the arguments in the repository and returns its output. Errors include what git wrote to
+// stderr.
+func gitOutput(repository string, args ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("git", append([]string{"-C", repository}, args...)...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	if err != nil {
+		message := strings.TrimSpace(stderr.String())
+		if message == "" {
+			return "", fmt.Errorf("git %s: %w", strings.Join(args, " "), err)
+		}
+		return "", fmt.Errorf("git %s: %w: %s", strings.Join(args, " "), err, message)
+	}
+	return stdout.String(), nil
+}
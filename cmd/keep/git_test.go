@@ -0,0 +1,189 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jojomi/keep"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+)
+
+// gitTestRepository creates a repository with a commit on each of the first four days of March 2024.
+func gitTestRepository(t *testing.T) string {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	t.Setenv("GIT_CONFIG_GLOBAL", os.DevNull)
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	t.Setenv("GIT_AUTHOR_NAME", "keep")
+	t.Setenv("GIT_AUTHOR_EMAIL", "keep@example.com")
+	t.Setenv("GIT_COMMITTER_NAME", "keep")
+	t.Setenv("GIT_COMMITTER_EMAIL", "keep@example.com")
+
+	dir := t.TempDir()
+	gitAt(t, dir, time.Time{}, "init", "--quiet", "--initial-branch", "main")
+	for day := 1; day <= 4; day++ {
+		gitAt(t, dir, gitTestDay(day), "commit", "--quiet", "--allow-empty", "--message", "nightly")
+	}
+	return dir
+}
+
+func gitTestDay(day int) time.Time {
+	return time.Date(2024, 3, day, 2, 0, 0, 0, time.UTC)
+}
+
+// gitAt runs git in dir, with the author, committer, and tagger date set to date unless it is zero.
+func gitAt(t *testing.T, dir string, date time.Time, args ...string) {
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	cmd.Env = os.Environ()
+	if !date.IsZero() {
+		cmd.Env = append(cmd.Env, "GIT_AUTHOR_DATE="+date.Format(time.RFC3339), "GIT_COMMITTER_DATE="+date.Format(time.RFC3339))
+	}
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %v: %v\n%s", args, err, output)
+	}
+}
+
+func TestListGitRefs_Tags(t *testing.T) {
+	dir := gitTestRepository(t)
+	gitAt(t, dir, time.Time{}, "tag", "nightly-2024-03-01", "HEAD~3")
+	gitAt(t, dir, time.Time{}, "tag", "nightly-2024-03-02", "HEAD~2")
+	// annotated tags are dated by their tagger
+	gitAt(t, dir, gitTestDay(5), "tag", "--annotate", "--message", "release", "nightly-2024-03-05", "HEAD~3")
+	gitAt(t, dir, time.Time{}, "tag", "release-1", "HEAD")
+
+	refs, err := listGitRefs(dir, "tags", []string{"nightly-*"})
+	assert.NoError(t, err)
+	assert.Len(t, refs, 3)
+	times := make(map[string]time.Time)
+	for _, ref := range refs {
+		times[ref.Name] = ref.Time.UTC()
+	}
+	assert.Equal(t, map[string]time.Time{
+		"nightly-2024-03-01": gitTestDay(1),
+		"nightly-2024-03-02": gitTestDay(2),
+		"nightly-2024-03-05": gitTestDay(5),
+	}, times)
+
+	refs, err = listGitRefs(dir, "tags", nil)
+	assert.NoError(t, err)
+	assert.Len(t, refs, 4)
+
+	assert.NoError(t, deleteGitRef(dir, "tags", "nightly-2024-03-01", false))
+	assert.NoError(t, deleteGitRef(dir, "tags", "nightly-2024-03-05", false))
+	assert.ErrorContains(t, deleteGitRef(dir, "tags", "nightly-2024-03-01", false), "not found")
+	refs, err = listGitRefs(dir, "tags", []string{"nightly-*"})
+	assert.NoError(t, err)
+	assert.Len(t, refs, 1)
+	assert.Equal(t, "nightly-2024-03-02", refs[0].Name)
+
+	_, err = listGitRefs(t.TempDir(), "tags", nil)
+	assert.Error(t, err)
+	_, err = listGitRefs(dir, "tags", []string{"["})
+	assert.Error(t, err)
+}
+
+func TestListGitRefs_Branches(t *testing.T) {
+	dir := gitTestRepository(t)
+	gitAt(t, dir, time.Time{}, "branch", "merged", "HEAD~2")
+	gitAt(t, dir, time.Time{}, "checkout", "--quiet", "-b", "unmerged", "HEAD~1")
+	gitAt(t, dir, gitTestDay(5), "commit", "--quiet", "--allow-empty", "--message", "experiment")
+	gitAt(t, dir, time.Time{}, "checkout", "--quiet", "main")
+	// branches with an upstream are never pruned
+	gitAt(t, dir, time.Time{}, "branch", "tracking", "HEAD")
+	gitAt(t, dir, time.Time{}, "branch", "--set-upstream-to", "main", "tracking")
+
+	refs, err := listGitRefs(dir, "branches", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []gitRef{
+		{Name: "merged", Time: refs[0].Time},
+		{Name: "unmerged", Time: refs[1].Time},
+	}, refs)
+	assert.Equal(t, gitTestDay(2), refs[0].Time.UTC())
+	assert.Equal(t, gitTestDay(5), refs[1].Time.UTC())
+
+	assert.NoError(t, deleteGitRef(dir, "branches", "merged", false))
+	assert.ErrorContains(t, deleteGitRef(dir, "branches", "unmerged", false), "not fully merged")
+	assert.NoError(t, deleteGitRef(dir, "branches", "unmerged", true))
+	refs, err = listGitRefs(dir, "branches", nil)
+	assert.NoError(t, err)
+	assert.Empty(t, refs)
+}
+
+func TestGitRef_Jailhouse(t *testing.T) {
+	// tags created by the same commit share a time, their names decide the order
+	refs := []gitRef{
+		{Name: "nightly-b", Time: gitTestDay(1)},
+		{Name: "nightly-a", Time: gitTestDay(1)},
+		{Name: "nightly-c", Time: gitTestDay(2)},
+	}
+	names := func(elements []*keep.JailhouseTimeResource[gitRef]) []string {
+		result := make([]string, len(elements))
+		for i, element := range elements {
+			result[i] = element.TimeResource.String()
+		}
+		return result
+	}
+
+	forward := keep.NewDefaultJailhouse[gitRef]().AddElements(refs...)
+	backward := keep.NewDefaultJailhouse[gitRef]().AddElements(refs[2], refs[1], refs[0])
+	assert.Equal(t, names(forward.Elements()), names(backward.Elements()))
+	assert.Equal(t, "nightly-c", names(forward.Elements())[0])
+}
+
+// gitTestCmd returns `keep git` with the flags shared by the root command, parsed from args.
+func gitTestCmd(t *testing.T, args ...string) *cobra.Command {
+	cmd := getGitCmd()
+	addRootFlags(cmd.Flags(), pflag.NewFlagSet("root", pflag.ContinueOnError))
+	assert.NoError(t, cmd.Flags().Parse(args))
+	return cmd
+}
+
+func TestRunGit(t *testing.T) {
+	dir := gitTestRepository(t)
+	for day := 1; day <= 4; day++ {
+		gitAt(t, dir, time.Time{}, "tag", gitTestDay(day).Format("nightly-2006-01-02"), fmt.Sprintf("HEAD~%d", 4-day))
+	}
+	tagNames := func() []string {
+		refs, err := listGitRefs(dir, "tags", nil)
+		assert.NoError(t, err)
+		names := make([]string, len(refs))
+		for i, ref := range refs {
+			names[i] = ref.Name
+		}
+		return names
+	}
+	all := []string{"nightly-2024-03-01", "nightly-2024-03-02", "nightly-2024-03-03", "nightly-2024-03-04"}
+
+	// flags about files are usage errors
+	for _, args := range [][]string{{"--output", "json"}, {"--time-source", "mtime"}, {"--report", "pdf"}} {
+		err := runGit(gitTestCmd(t, append(args, "-f", "-r", "1 last")...), []string{dir})
+		assert.Equal(t, exitUsage, exitCode(err), args)
+	}
+
+	// --print0 lists the free refs without deleting them
+	assert.NoError(t, runGit(gitTestCmd(t, "--print0", "-f", "-r", "1 last"), []string{dir}))
+	assert.Equal(t, all, tagNames())
+
+	auditPath := filepath.Join(t.TempDir(), "audit.jsonl")
+	assert.NoError(t, runGit(gitTestCmd(t, "--audit-log", auditPath, "-f", "-r", "1 last"), []string{dir}))
+	assert.Equal(t, all[3:], tagNames())
+
+	records, runs, err := readAuditLog(auditPath, auditFilter{})
+	assert.NoError(t, err)
+	assert.Len(t, runs, 1)
+	paths := make([]string, len(records))
+	for i, record := range records {
+		assert.Equal(t, "removed", record.Type)
+		paths[i] = record.Path
+	}
+	assert.ElementsMatch(t, []string{"refs/tags/nightly-2024-03-01", "refs/tags/nightly-2024-03-02", "refs/tags/nightly-2024-03-03"}, paths)
+	assert.Equal(t, "delete", runs[records[0].Run].Action)
+}
@@ -3,15 +3,21 @@ module github.com/jojomi/keep/cmd/keep
 go 1.22.2
 
 require (
+	github.com/djherbis/times v1.6.0
 	github.com/jojomi/keep v0.0.0-20240421090506-7ab37909f8fd
 	github.com/spf13/cobra v1.8.0
+	github.com/spf13/pflag v1.0.5
+	github.com/stretchr/testify v1.9.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
-	github.com/djherbis/times v1.6.0 // indirect
+	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/inconshreveable/mousetrap v1.1.0 // indirect
 	github.com/juju/errors v1.0.0 // indirect
-	github.com/spf13/pflag v1.0.5 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
 	golang.org/x/exp v0.0.0-20240416160154-fe59bbe5cc7f // indirect
 	golang.org/x/sys v0.19.0 // indirect
 )
+
+replace github.com/jojomi/keep => ../..
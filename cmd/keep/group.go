@@ -0,0 +1,189 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jojomi/keep"
+)
+
+// fileElement is a file or directory of a target, together with the companions grouped with it by --group-by.
+type fileElement struct {
+	Filename string
+	Time     time.Time
+	// Companions are further files belonging to Filename, like checksums, signatures, or logs. They are kept and
+	// freed together with it.
+	Companions []string
+}
+
+func (x fileElement) GetTime() time.Time {
+	return x.Time
+}
+
+// TieBreakKey orders elements with the same time by their filename.
+func (x fileElement) TieBreakKey() string {
+	return x.Filename
+}
+
+func (x fileElement) String() string {
+	return displayPath(x)
+}
+
+// Paths returns the filename followed by the companions.
+func (x fileElement) Paths() []string {
+	return append([]string{x.Filename}, x.Companions...)
+}
+
+// parseGroupPattern compiles the --group-by pattern. The stem is the named group "stem", the first group, or the
+// whole match, in that order.
+func parseGroupPattern(expr string) (*regexp.Regexp, error) {
+	if expr == "" {
+		return nil, nil
+	}
+	pattern, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, fmt.Errorf("compiling --group-by pattern %q: %w", expr, err)
+	}
+	return pattern, nil
+}
+
+// fileStem returns the stem of the file name in path, false if pattern does not match.
+func fileStem(pattern *regexp.Regexp, path string) (string, bool) {
+	match := pattern.FindStringSubmatch(filepath.Base(path))
+	if match == nil {
+		return "", false
+	}
+	if index := pattern.SubexpIndex("stem"); index >= 0 {
+		return match[index], true
+	}
+	if len(match) > 1 {
+		return match[1], true
+	}
+	return match[0], true
+}
+
+// groupCompanions merges files of the same directory sharing a stem into one element. The largest file represents
+// the group and the others become its companions, the time of the group is the earliest time of its files. Files
+// without a stem stay elements of their own.
+func groupCompanions(files []fileElement, pattern *regexp.Regexp, dirs bool) []fileElement {
+	if pattern == nil {
+		return files
+	}
+
+	type groupKey struct {
+		dir  string
+		stem string
+	}
+	var (
+		groups  = make(map[groupKey][]fileElement)
+		order   = make([]groupKey, 0)
+		grouped = make([]fileElement, 0, len(files))
+	)
+	for _, file := range files {
+		stem, ok := fileStem(pattern, file.Filename)
+		if !ok {
+			grouped = append(grouped, file)
+			continue
+		}
+		key := groupKey{filepath.Dir(file.Filename), stem}
+		if _, ok := groups[key]; !ok {
+			order = append(order, key)
+		}
+		groups[key] = append(groups[key], file)
+	}
+
+	for _, key := range order {
+		members := groups[key]
+		sizes := make(map[string]int64, len(members))
+		for _, member := range members {
+			sizes[member.Filename] = elementSize(member.Filename, dirs)
+		}
+		sort.SliceStable(members, func(i, j int) bool {
+			if sizes[members[i].Filename] != sizes[members[j].Filename] {
+				return sizes[members[i].Filename] > sizes[members[j].Filename]
+			}
+			return members[i].Filename < members[j].Filename
+		})
+
+		group := fileElement{
+			Filename: members[0].Filename,
+			Time:     members[0].Time,
+		}
+		for _, member := range members[1:] {
+			group.Companions = append(group.Companions, member.Filename)
+			if member.Time.Before(group.Time) {
+				group.Time = member.Time
+			}
+		}
+		grouped = append(grouped, group)
+	}
+	return grouped
+}
+
+// disposeFile disposes of the companions of the file and then the file itself, so a failure never leaves companions
+// without their file. The error of a failure lists the members left in place.
+func disposeFile(dispose disposal, root string, file fileElement, dir bool) error {
+	paths := file.disposalOrder()
+	for i, path := range paths {
+		err := dispose(root, path, dir)
+		if err == nil {
+			continue
+		}
+		if len(paths) == 1 {
+			return err
+		}
+		return fmt.Errorf("%w, left in place: %s", err, strings.Join(paths[i:], ", "))
+	}
+	return nil
+}
+
+// disposalOrder returns the companions followed by the filename.
+func (x fileElement) disposalOrder() []string {
+	return append(append([]string{}, x.Companions...), x.Filename)
+}
+
+// displayPath returns the path of a file for listings, mentioning its companions.
+func displayPath(file fileElement) string {
+	switch len(file.Companions) {
+	case 0:
+		return file.Filename
+	case 1:
+		return file.Filename + " (+1 companion)"
+	default:
+		return fmt.Sprintf("%s (+%d companions)", file.Filename, len(file.Companions))
+	}
+}
+
+// filesSize returns the total size of the file and its companions.
+func filesSize(file fileElement, dirs bool) int64 {
+	var size int64
+	for _, path := range file.Paths() {
+		size += elementSize(path, dirs)
+	}
+	return size
+}
+
+// expandCompanions returns one element per file, companions preceding the file they belong to with the same time
+// and tags, see disposeFile.
+func expandCompanions(elements []*keep.JailhouseTimeResource[fileElement]) []*keep.JailhouseTimeResource[fileElement] {
+	expanded := make([]*keep.JailhouseTimeResource[fileElement], 0, len(elements))
+	for _, element := range elements {
+		if len(element.TimeResource.Companions) == 0 {
+			expanded = append(expanded, element)
+			continue
+		}
+		for _, path := range element.TimeResource.disposalOrder() {
+			member := *element
+			member.TimeResource = fileElement{
+				Filename: path,
+				Time:     element.TimeResource.Time,
+			}
+			expanded = append(expanded, &member)
+		}
+	}
+	return expanded
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jojomi/keep"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileStem(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern string
+		path    string
+		want    string
+		wantOK  bool
+	}{
+		{name: "whole match", pattern: `^[^.]+`, path: "/b/backup-1.tar.zst.sha256", want: "backup-1", wantOK: true},
+		{name: "first group", pattern: `^(.+?)\.(tar|log)`, path: "/b/backup-1.log", want: "backup-1", wantOK: true},
+		{name: "named group", pattern: `^(backup)-(?P<stem>\d+)`, path: "/b/backup-1.tar", want: "1", wantOK: true},
+		{name: "no match", pattern: `^backup`, path: "/b/other.tar", wantOK: false},
+		{name: "name only", pattern: `^b`, path: "/backups/x.tar", wantOK: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pattern, err := parseGroupPattern(tt.pattern)
+			assert.NoError(t, err)
+			stem, ok := fileStem(pattern, tt.path)
+			assert.Equal(t, tt.wantOK, ok)
+			assert.Equal(t, tt.want, stem)
+		})
+	}
+
+	pattern, err := parseGroupPattern("")
+	assert.NoError(t, err)
+	assert.Nil(t, pattern)
+	_, err = parseGroupPattern("(")
+	assert.Error(t, err)
+}
+
+func TestFileElement_Paths(t *testing.T) {
+	tests := []struct {
+		name string
+		file fileElement
+		want []string
+	}{
+		{name: "single", file: fileElement{Filename: "a.tar"}, want: []string{"a.tar"}},
+		{name: "companions", file: fileElement{Filename: "a.tar", Companions: []string{"a.tar.sha256", "a.log"}}, want: []string{"a.tar", "a.tar.sha256", "a.log"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.file.Paths())
+		})
+	}
+}
+
+func TestGroupCompanions(t *testing.T) {
+	dir := t.TempDir()
+	day := time.Date(2024, 3, 1, 2, 0, 0, 0, time.UTC)
+	files := []fileElement{
+		{Filename: filepath.Join(dir, "backup-1.log"), Time: day},
+		{Filename: filepath.Join(dir, "backup-1.tar.zst"), Time: day.Add(time.Minute)},
+		{Filename: filepath.Join(dir, "backup-1.tar.zst.sha256"), Time: day.Add(2 * time.Minute)},
+		{Filename: filepath.Join(dir, "backup-2.tar.zst"), Time: day.AddDate(0, 0, 1)},
+		{Filename: filepath.Join(dir, "sub", "backup-1.tar.zst"), Time: day},
+		{Filename: filepath.Join(dir, "README"), Time: day},
+	}
+	sizes := map[string]int{"backup-1.log": 10, "backup-1.tar.zst": 100, "backup-1.tar.zst.sha256": 64, "backup-2.tar.zst": 100, "README": 1}
+	assert.NoError(t, os.MkdirAll(filepath.Join(dir, "sub"), 0o755))
+	for _, file := range files {
+		assert.NoError(t, os.WriteFile(file.Filename, make([]byte, sizes[filepath.Base(file.Filename)]), 0o644))
+	}
+
+	// without a pattern, nothing is grouped
+	assert.Equal(t, files, groupCompanions(files, nil, false))
+
+	pattern, err := parseGroupPattern(`^backup-\d+`)
+	assert.NoError(t, err)
+	grouped := groupCompanions(files, pattern, false)
+	assert.Equal(t, []fileElement{
+		{Filename: filepath.Join(dir, "README"), Time: day},
+		{
+			Filename:   filepath.Join(dir, "backup-1.tar.zst"),
+			Time:       day,
+			Companions: []string{filepath.Join(dir, "backup-1.tar.zst.sha256"), filepath.Join(dir, "backup-1.log")},
+		},
+		{Filename: filepath.Join(dir, "backup-2.tar.zst"), Time: day.AddDate(0, 0, 1)},
+		{Filename: filepath.Join(dir, "sub", "backup-1.tar.zst"), Time: day},
+	}, grouped)
+	assert.Equal(t, int64(174), filesSize(grouped[1], false))
+	assert.Equal(t, filepath.Join(dir, "backup-1.tar.zst")+" (+2 companions)", displayPath(grouped[1]))
+
+	// the group is disposed of as a whole, the file last
+	elements := expandCompanions([]*keep.JailhouseTimeResource[fileElement]{keep.NewJailhouseTimeResource(grouped[1])})
+	assert.Len(t, elements, 3)
+	assert.Equal(t, filepath.Join(dir, "backup-1.log"), elements[1].TimeResource.Filename)
+	assert.Equal(t, day, elements[1].GetTime())
+	assert.Equal(t, filepath.Join(dir, "backup-1.tar.zst"), elements[2].TimeResource.Filename)
+
+	disposed := make([]string, 0)
+	failing := func(root, path string, dir bool) error {
+		disposed = append(disposed, filepath.Base(path))
+		if filepath.Base(path) == "backup-1.log" {
+			return errors.New("permission denied")
+		}
+		return nil
+	}
+	err = disposeFile(failing, dir, grouped[1], false)
+	assert.EqualError(t, err, "permission denied, left in place: "+filepath.Join(dir, "backup-1.log")+", "+filepath.Join(dir, "backup-1.tar.zst"))
+	assert.Equal(t, []string{"backup-1.tar.zst.sha256", "backup-1.log"}, disposed)
+
+	assert.NoError(t, disposeFile(removeElement, dir, grouped[1], false))
+	for _, path := range grouped[1].Paths() {
+		assert.NoFileExists(t, path)
+	}
+}
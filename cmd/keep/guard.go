@@ -0,0 +1,152 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jojomi/keep"
+	"github.com/spf13/pflag"
+)
+
+// guardNames are the guards against catastrophic deletion, see --override-guard.
+var guardNames = []string{"max-free-percent", "max-free", "min-remaining", "all-free", "newest", "same-time"}
+
+// guardLimits configures the guards, zero values disable the limits.
+type guardLimits struct {
+	MaxFreePercent float64
+	MaxFree        int
+	MinRemaining   int
+	// Overrides are the names of the guards not to check.
+	Overrides []string
+}
+
+// guardViolation describes a guard that fired.
+type guardViolation struct {
+	Guard   string
+	Message string
+}
+
+func (x guardViolation) String() string {
+	return fmt.Sprintf("guard %s fired: %s", x.Guard, x.Message)
+}
+
+// checkGuards returns the guards that fire for the given elements after applying requirements anchored at anchor
+// for referenceDate. Besides the configurable limits, freeing all elements, freeing the newest element, and freeing
+// elements all sharing the same time are considered signs of a wrong clock or unreadable times.
+func checkGuards[T prunable](limits guardLimits, elements []*keep.JailhouseTimeResource[T], anchor keep.Anchor, referenceDate time.Time) []guardViolation {
+	removals := make([]*keep.JailhouseTimeResource[T], 0)
+	for _, element := range elements {
+		if element.IsFree() {
+			removals = append(removals, element)
+		}
+	}
+	return checkRemovalGuards(limits, elements, removals, anchor, referenceDate)
+}
+
+// checkRemovalGuards works like checkGuards, but for removing exactly removals out of elements instead of the free
+// ones, e.g. after --review.
+func checkRemovalGuards[T prunable](limits guardLimits, elements, removals []*keep.JailhouseTimeResource[T], anchor keep.Anchor, referenceDate time.Time) []guardViolation {
+	violations := make([]guardViolation, 0)
+	if len(elements) == 0 {
+		return violations
+	}
+
+	removed := make(map[*keep.JailhouseTimeResource[T]]bool, len(removals))
+	for _, element := range removals {
+		removed[element] = true
+	}
+	free := len(removed)
+	remaining := len(elements) - free
+	percent := float64(free) * 100 / float64(len(elements))
+
+	if limits.MaxFreePercent > 0 && percent > limits.MaxFreePercent {
+		violations = append(violations, guardViolation{"max-free-percent", fmt.Sprintf("%.1f%% of %d elements would be freed, at most %.1f%% allowed", percent, len(elements), limits.MaxFreePercent)})
+	}
+	if limits.MaxFree > 0 && free > limits.MaxFree {
+		violations = append(violations, guardViolation{"max-free", fmt.Sprintf("%d elements would be freed, at most %d allowed", free, limits.MaxFree)})
+	}
+	if limits.MinRemaining > 0 && remaining < limits.MinRemaining {
+		violations = append(violations, guardViolation{"min-remaining", fmt.Sprintf("%d elements would remain, at least %d required", remaining, limits.MinRemaining)})
+	}
+	// independent of the anchor, nothing can be restored if everything is gone
+	if remaining == 0 {
+		violations = append(violations, guardViolation{"all-free", fmt.Sprintf("all %d elements would be freed, is the clock wrong?", len(elements))})
+	}
+	// elements are sorted youngest first. Anchored at the youngest element, it is always kept unless it lies in the
+	// future, anchored at the oldest one, a cell may be represented by an older element.
+	newest := elements[0]
+	if removed[newest] && (anchor == keep.AnchorYoungest || newest.GetTime().After(referenceDate)) {
+		violations = append(violations, guardViolation{"newest", fmt.Sprintf("the newest element %s would be freed, is the clock wrong?", newest.TimeResource)})
+	}
+	if free > 0 && len(elements) > 1 && elements[0].GetTime().Equal(elements[len(elements)-1].GetTime()) {
+		violations = append(violations, guardViolation{"same-time", fmt.Sprintf("all %d elements share the time %s, are the times unreadable?", len(elements), elements[0].GetTime())})
+	}
+
+	result := make([]guardViolation, 0, len(violations))
+	for _, violation := range violations {
+		if !limits.overridden(violation.Guard) {
+			result = append(result, violation)
+		}
+	}
+	return result
+}
+
+func (x guardLimits) overridden(guard string) bool {
+	for _, override := range x.Overrides {
+		if override == guard || override == "all" {
+			return true
+		}
+	}
+	return false
+}
+
+// addGuardFlags defines the flags setting the guards, see checkGuards.
+func addGuardFlags(flags *pflag.FlagSet) {
+	flags.Float64("max-free-percent", 0, "abort if more than this percentage of the elements would be freed, 0 disables this guard")
+	flags.Int("max-free", 0, "abort if more than this number of elements would be freed, 0 disables this guard")
+	flags.Int("min-remaining", 0, "abort if fewer than this number of elements would remain, 0 disables this guard")
+	flags.StringSlice("override-guard", nil, "ignore these guards: max-free-percent, max-free, min-remaining, all-free (all elements would be freed), newest (the newest element would be freed), same-time (all elements share one time), or all")
+}
+
+// parseGuardLimits reads the flags defined by addGuardFlags.
+func parseGuardLimits(flags *pflag.FlagSet) (guardLimits, error) {
+	var (
+		limits guardLimits
+		err    error
+	)
+
+	limits.MaxFreePercent, err = flags.GetFloat64("max-free-percent")
+	if err != nil {
+		return limits, err
+	}
+
+	limits.MaxFree, err = flags.GetInt("max-free")
+	if err != nil {
+		return limits, err
+	}
+
+	limits.MinRemaining, err = flags.GetInt("min-remaining")
+	if err != nil {
+		return limits, err
+	}
+
+	limits.Overrides, err = flags.GetStringSlice("override-guard")
+	if err != nil {
+		return limits, err
+	}
+	return limits, validateGuardNames(limits.Overrides)
+}
+
+// validateGuardNames returns an error for names not naming a guard.
+func validateGuardNames(names []string) error {
+	for _, name := range names {
+		valid := name == "all"
+		for _, guard := range guardNames {
+			valid = valid || name == guard
+		}
+		if !valid {
+			return fmt.Errorf("unknown guard %q, try %v or all", name, guardNames)
+		}
+	}
+	return nil
+}
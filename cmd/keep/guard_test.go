@@ -0,0 +1,104 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jojomi/keep"
+	"github.com/stretchr/testify/assert"
+)
+
+// guardElements returns ten daily elements, youngest first, with the first kept ones tagged.
+func guardElements(kept int) []*keep.JailhouseTimeResource[fileElement] {
+	elements := make([]*keep.JailhouseTimeResource[fileElement], 10)
+	for i := range elements {
+		elements[i] = keep.NewJailhouseTimeResource(fileElement{
+			Filename: time.Date(2024, 3, 10-i, 0, 0, 0, 0, time.UTC).Format("2006-01-02"),
+			Time:     time.Date(2024, 3, 10-i, 0, 0, 0, 0, time.UTC),
+		})
+		if i < kept {
+			elements[i].AddTag(keep.TimeRangeTagFrom(keep.LAST, uint16(i+1)))
+		}
+	}
+	return elements
+}
+
+func TestCheckGuards(t *testing.T) {
+	sameTime := guardElements(5)
+	for _, element := range sameTime {
+		element.TimeResource.Time = sameTime[0].GetTime()
+	}
+	newestFree := guardElements(5)
+	newestFree[0].ClearTags()
+	allKept := guardElements(10)
+	for _, element := range allKept {
+		element.TimeResource.Time = allKept[0].GetTime()
+	}
+	referenceDate := time.Date(2024, 3, 11, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		limits   guardLimits
+		elements []*keep.JailhouseTimeResource[fileElement]
+		anchor   keep.Anchor
+		want     []string
+	}{
+		{name: "no limits", elements: guardElements(2), want: []string{}},
+		{name: "empty", limits: guardLimits{MinRemaining: 3}, want: []string{}},
+		{name: "max-free-percent", limits: guardLimits{MaxFreePercent: 50}, elements: guardElements(4), want: []string{"max-free-percent"}},
+		{name: "max-free-percent at limit", limits: guardLimits{MaxFreePercent: 60}, elements: guardElements(4), want: []string{}},
+		{name: "max-free", limits: guardLimits{MaxFree: 5}, elements: guardElements(4), want: []string{"max-free"}},
+		{name: "min-remaining", limits: guardLimits{MinRemaining: 5}, elements: guardElements(4), want: []string{"min-remaining"}},
+		{name: "all limits", limits: guardLimits{MaxFreePercent: 10, MaxFree: 1, MinRemaining: 9}, elements: guardElements(4), want: []string{"max-free-percent", "max-free", "min-remaining"}},
+		{name: "newest", elements: newestFree, want: []string{"newest"}},
+		{name: "newest anchored at oldest", elements: newestFree, anchor: keep.AnchorOldest, want: []string{}},
+		{name: "all-free", elements: guardElements(0), want: []string{"all-free", "newest"}},
+		{name: "all-free anchored at oldest", elements: guardElements(0), anchor: keep.AnchorOldest, want: []string{"all-free"}},
+		{name: "same-time", elements: sameTime, want: []string{"same-time"}},
+		{name: "same-time without removals", elements: allKept, want: []string{}},
+		{name: "overridden", limits: guardLimits{MaxFree: 5, Overrides: []string{"max-free", "newest"}}, elements: newestFree, want: []string{}},
+		{name: "all overridden", limits: guardLimits{MaxFree: 1, Overrides: []string{"all"}}, elements: newestFree, want: []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := make([]string, 0)
+			for _, violation := range checkGuards(tt.limits, tt.elements, tt.anchor, referenceDate) {
+				got = append(got, violation.Guard)
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestCheckGuards_Future(t *testing.T) {
+	elements := guardElements(5)
+	elements[0].ClearTags()
+
+	// anchored at the oldest element, a free newest element is suspicious only if it lies in the future
+	referenceDate := time.Date(2024, 3, 9, 12, 0, 0, 0, time.UTC)
+	violations := checkGuards(guardLimits{}, elements, keep.AnchorOldest, referenceDate)
+	assert.Len(t, violations, 1)
+	assert.Equal(t, "newest", violations[0].Guard)
+}
+
+func TestCheckRemovalGuards(t *testing.T) {
+	elements := guardElements(5)
+	referenceDate := time.Date(2024, 3, 11, 0, 0, 0, 0, time.UTC)
+	limits := guardLimits{MaxFree: 2}
+
+	// a review keeping free elements satisfies the limits
+	assert.Empty(t, checkRemovalGuards(limits, elements, elements[8:], keep.AnchorYoungest, referenceDate))
+
+	// a review removing kept elements is checked like the requirements freed them
+	got := make([]string, 0)
+	for _, violation := range checkRemovalGuards(limits, elements, elements[:3], keep.AnchorYoungest, referenceDate) {
+		got = append(got, violation.Guard)
+	}
+	assert.Equal(t, []string{"max-free", "newest"}, got)
+}
+
+func TestValidateGuardNames(t *testing.T) {
+	assert.NoError(t, validateGuardNames(nil))
+	assert.NoError(t, validateGuardNames([]string{"all", "newest", "same-time"}))
+	assert.Error(t, validateGuardNames([]string{"newest", "oldest"}))
+}
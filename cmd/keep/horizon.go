@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jojomi/keep"
+	"github.com/spf13/cobra"
+)
+
+func getHorizonCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "horizon",
+		Short: "show how far back the requirements reach and how many files they keep",
+		RunE:  runHorizon,
+	}
+
+	flags := cmd.Flags()
+	flags.Duration("interval", time.Hour, "assumed interval between backups")
+	flags.Duration("jitter", 0, "assumed deviation from the interval in both directions")
+
+	return cmd
+}
+
+func runHorizon(cmd *cobra.Command, args []string) error {
+	cmd.SilenceUsage = true
+	err := applyEnvironment(cmd.Flags())
+	if err != nil {
+		return withExitCode(exitUsage, err)
+	}
+	env, err := parseEnvHorizon(cmd, args)
+	if err != nil {
+		return withExitCode(exitUsage, err)
+	}
+
+	reqs, err := parseRequirements(env.Requirements)
+	if err != nil {
+		return err
+	}
+	fmt.Println(reqs)
+
+	jh, err := newJailhouse[keep.File](env.Anchor, env.AnchorEpoch)
+	if err != nil {
+		return err
+	}
+	horizon := jh.Horizon(*reqs, env.Interval, env.Jitter, time.Now())
+	fmt.Println(horizon)
+	return nil
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// lockFileName is the name of the lock file inside a target directory, see --lock.
+const lockFileName = ".keep.lock"
+
+// lockPollInterval is the time between attempts to get a lock with --lock wait.
+const lockPollInterval = 100 * time.Millisecond
+
+// targetLock is an advisory lock on a target directory, held by at most one keep process at a time.
+type targetLock struct {
+	file *os.File
+}
+
+// lockFilePath returns the lock file for the target directory root: inside root itself, or named after the
+// absolute path of root inside lockDir, if given.
+func lockFilePath(root, lockDir string) (string, error) {
+	if lockDir == "" {
+		return filepath.Join(root, lockFileName), nil
+	}
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(absRoot))
+	return filepath.Join(lockDir, "keep-"+hex.EncodeToString(sum[:8])+".lock"), nil
+}
+
+// acquireLock locks the lock file at path. With mode fail it gives up immediately if another process holds the
+// lock, with mode wait it retries until timeout passes (forever if 0). The error names the process holding the lock.
+func acquireLock(path, mode string, timeout time.Duration) (*targetLock, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("opening lock file: %w", err)
+	}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		locked, err := tryLockFile(file)
+		if err != nil {
+			file.Close()
+			return nil, fmt.Errorf("locking %s: %w", path, err)
+		}
+		if locked {
+			break
+		}
+		if mode != "wait" || (timeout > 0 && time.Now().After(deadline)) {
+			err = lockHeldError(file, path)
+			file.Close()
+			return nil, err
+		}
+		time.Sleep(lockPollInterval)
+	}
+
+	// the lock file of a process that died is stale, flock released it with the process
+	if pid, ok := readLockPID(file); ok && pid != os.Getpid() && !processAlive(pid) {
+		fmt.Fprintf(os.Stderr, "Taking over stale lock %s of PID %d\n", path, pid)
+	}
+
+	err = writeLockPID(file)
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("writing lock file %s: %w", path, err)
+	}
+	return &targetLock{
+		file: file,
+	}, nil
+}
+
+// Release releases the lock. The lock file itself is kept, removing it would race with processes waiting for it.
+func (x *targetLock) Release() error {
+	if x == nil {
+		return nil
+	}
+	err := x.file.Truncate(0)
+	if closeErr := x.file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+func lockHeldError(file *os.File, path string) error {
+	pid, ok := readLockPID(file)
+	switch {
+	case !ok:
+		return fmt.Errorf("target is locked by another keep process (lock file %s)", path)
+	case !processAlive(pid):
+		return fmt.Errorf("target is locked by PID %d, which is not running on this host (lock file %s)", pid, path)
+	default:
+		return fmt.Errorf("target is locked by keep process with PID %d (lock file %s)", pid, path)
+	}
+}
+
+func readLockPID(file *os.File) (int, bool) {
+	data, err := io.ReadAll(io.NewSectionReader(file, 0, 32))
+	if err != nil {
+		return 0, false
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	return pid, err == nil && pid > 0
+}
+
+func writeLockPID(file *os.File) error {
+	err := file.Truncate(0)
+	if err != nil {
+		return err
+	}
+	_, err = file.WriteAt([]byte(strconv.Itoa(os.Getpid())+"\n"), 0)
+	if err != nil {
+		return err
+	}
+	return file.Sync()
+}
@@ -0,0 +1,18 @@
+//go:build !unix
+
+package main
+
+import (
+	"errors"
+	"os"
+)
+
+// tryLockFile fails, advisory locks are only supported on unix-like systems.
+func tryLockFile(_ *os.File) (bool, error) {
+	return false, errors.New("locking is not supported on this system, use --lock off")
+}
+
+// processAlive assumes every process is running, since it cannot be checked on this system.
+func processAlive(_ int) bool {
+	return true
+}
@@ -0,0 +1,67 @@
+//go:build unix
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLockFilePath(t *testing.T) {
+	path, err := lockFilePath("/backups/db", "")
+	assert.NoError(t, err)
+	assert.Equal(t, "/backups/db/.keep.lock", path)
+
+	path, err = lockFilePath("/backups/db", "/run/keep")
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(path, "/run/keep/keep-"))
+	other, err := lockFilePath("/backups/www", "/run/keep")
+	assert.NoError(t, err)
+	assert.NotEqual(t, path, other)
+}
+
+func TestAcquireLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), lockFileName)
+
+	first, err := acquireLock(path, "fail", 0)
+	assert.NoError(t, err)
+	content, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, fmt.Sprintf("%d\n", os.Getpid()), string(content))
+
+	// flock is bound to the open file, so a second attempt fails even in the same process
+	_, err = acquireLock(path, "fail", 0)
+	assert.ErrorContains(t, err, fmt.Sprintf("locked by keep process with PID %d", os.Getpid()))
+
+	start := time.Now()
+	_, err = acquireLock(path, "wait", 300*time.Millisecond)
+	assert.Error(t, err)
+	assert.GreaterOrEqual(t, time.Since(start), 300*time.Millisecond)
+
+	// waiting succeeds once the lock is released
+	go func() {
+		time.Sleep(200 * time.Millisecond)
+		assert.NoError(t, first.Release())
+	}()
+	lock, err := acquireLock(path, "wait", 0)
+	assert.NoError(t, err)
+	assert.NoError(t, lock.Release())
+}
+
+func TestAcquireLock_Stale(t *testing.T) {
+	path := filepath.Join(t.TempDir(), lockFileName)
+	// PIDs are limited far below this on all common systems
+	assert.NoError(t, os.WriteFile(path, []byte("2147483646\n"), 0o644))
+
+	lock, err := acquireLock(path, "fail", 0)
+	assert.NoError(t, err)
+	assert.NoError(t, lock.Release())
+	assert.False(t, processAlive(2147483646))
+	assert.True(t, processAlive(os.Getpid()))
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+func getLogCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "log",
+		Short: "show the elements removed according to the audit log",
+		Args:  cobra.NoArgs,
+		RunE:  runLog,
+	}
+
+	flags := cmd.Flags()
+	flags.String("since", "", "only show elements removed at or after this time (e.g. 2024-03-01 or 2024-03-01T12:00:00Z)")
+	flags.String("until", "", "only show elements removed before this time, dates include the whole day")
+	flags.StringSlice("name", nil, "only show elements whose name or path matches one of these glob patterns")
+	flags.Bool("failed", false, "only show elements that could not be removed")
+
+	return cmd
+}
+
+func runLog(cmd *cobra.Command, args []string) error {
+	cmd.SilenceUsage = true
+	err := applyEnvironment(cmd.Flags())
+	if err != nil {
+		return withExitCode(exitUsage, err)
+	}
+	env, err := parseEnvLog(cmd, args)
+	if err != nil {
+		return withExitCode(exitUsage, err)
+	}
+
+	records, runs, err := readAuditLog(env.AuditLog, env.Filter)
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	for _, record := range records {
+		if env.Output == "ndjson" {
+			if err := encoder.Encode(record); err != nil {
+				return err
+			}
+			continue
+		}
+
+		run := runs[record.Run]
+		status := run.Action
+		if record.Type == "failed" {
+			status = "FAILED " + run.Action
+		}
+		line := fmt.Sprintf("%s %s %s", record.Time.Local().Format(time.RFC3339), status, record.Path)
+		if record.Size != nil && record.ElementTime != nil {
+			line += fmt.Sprintf(" (%d bytes, from %s)", *record.Size, record.ElementTime.Local().Format(time.RFC3339))
+		}
+		line += fmt.Sprintf(" by %s@%s", run.User, run.Host)
+		if run.Requirements != nil {
+			line += fmt.Sprintf(" keeping %s", run.Requirements)
+		}
+		if record.Error != "" {
+			line += ": " + record.Error
+		}
+		fmt.Println(line)
+	}
+	return nil
+}
@@ -2,130 +2,489 @@ package main
 
 import (
 	"bufio"
+	"context"
+	"errors"
 	"fmt"
-	"github.com/djherbis/times"
 	"github.com/jojomi/keep"
 	"github.com/spf13/cobra"
-	"log"
+	"github.com/spf13/pflag"
+	"io"
 	"os"
+	"os/signal"
 	"strings"
 	"time"
 )
 
+// stdin is shared by all prompts, so buffered input is not lost between them.
+var stdin = bufio.NewReader(os.Stdin)
+
 func main() {
 	rootCmd := cobra.Command{
-		Use: "keep",
-		Run: runRoot,
+		Use:  "keep [path...]",
+		Args: cobra.ArbitraryArgs,
+		RunE: runRoot,
+		// errors are printed by main, together with the exit code
+		SilenceErrors: true,
+	}
+	rootCmd.SetFlagErrorFunc(func(cmd *cobra.Command, err error) error {
+		return withExitCode(exitUsage, err)
+	})
+	addRootFlags(rootCmd.PersistentFlags(), rootCmd.Flags())
+
+	rootCmd.AddCommand(getHorizonCmd())
+	rootCmd.AddCommand(getRestoreCmd())
+	rootCmd.AddCommand(getRunCmd())
+	rootCmd.AddCommand(getConfigCmd())
+	rootCmd.AddCommand(getLogCmd())
+	rootCmd.AddCommand(getFilterCmd())
+	rootCmd.AddCommand(getPlanCmd())
+	rootCmd.AddCommand(getApplyCmd())
+	rootCmd.AddCommand(getGitCmd())
+
+	if err := rootCmd.Execute(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(exitCode(err))
 	}
+}
 
-	flags := rootCmd.PersistentFlags()
+// addRootFlags defines the flags of the root command, those shared with subcommands in flags and those for
+// selecting and disposing of files in rootFlags. Jobs from config files use the same flags.
+func addRootFlags(flags, rootFlags *pflag.FlagSet) {
 	flags.StringP("requirements", "r", "10 last, 14 days, 12 weeks, 12 months, 12 years", "keep config")
 	flags.Bool("print-requirements-only", false, "print perceived requirements")
 	flags.BoolP("dry-run", "n", false, "don't actually delete files, but show which would be deleted")
 	flags.BoolP("force", "f", false, "don't ask questions, just do it")
+	flags.BoolP("yes", "y", false, "remove without confirmation, same as --force")
+	flags.Int("confirm-threshold", 20, "from this number of elements on, confirming requires typing the number or yes instead of y, 0 disables this")
+	flags.String("anchor", "youngest", "anchor levels at the youngest or oldest element (youngest, oldest)")
+	flags.String("anchor-epoch", "", "fixed start of the level grid for --anchor oldest (e.g. 2024-01-01)")
+	flags.String("time-source", "birth", "file time to use: name, birth, mtime, ctime, atime, or an ordered fallback list like birth,mtime")
+	flags.String("time-pattern", "", "read times from file names or paths with this pattern (e.g. pg-2006-01-02T1504), implies --time-source name")
+	flags.String("time-pattern-type", "layout", "type of --time-pattern: layout (Go time layout), strftime, or regexp (named groups year, month, day, hour, minute, second)")
+	flags.String("time-location", "Local", "time zone for times read by --time-pattern")
+	flags.String("unmatched", "report", "handling of files without a time: skip (silently) or report")
+	flags.BoolP("verbose", "v", false, "print details like the time and time source of every file")
+	flags.StringSlice("report", nil, "render a timeline as kind[:path] with kind ascii, html, or svg (e.g. html:out.html)")
+	flags.StringP("output", "o", "text", "output format: text, json, ndjson, csv, or tsv; other formats than text move the human-readable output to stderr")
+	flags.Bool("print0", false, "only print the paths of free elements separated by NUL characters (e.g. for xargs -0), without removing them")
+	flags.Bool("reasons", false, "explain why every element is kept or free")
+	flags.String("audit-log", "", "append a JSON line per run and removed element to this file (e.g. /var/log/keep.jsonl), see keep log")
+	flags.Bool("detailed-exit-codes", false, "exit with code 8 if there was nothing to remove")
 
-	if err := rootCmd.Execute(); err != nil {
-		fmt.Fprintln(os.Stderr, err)
-		os.Exit(1)
-	}
+	rootFlags.Bool("review", false, "open the elements in $EDITOR to switch them between keep and delete before removing")
+
+	addTargetFlags(rootFlags)
+}
+
+// addTargetFlags defines the flags selecting files in a target directory and handling the free ones.
+func addTargetFlags(flags *pflag.FlagSet) {
+	// file selection
+	flags.BoolP("recursive", "R", false, "include files in subdirectories")
+	flags.StringSlice("include", nil, "only consider files whose name or relative path matches one of these glob patterns")
+	flags.StringSlice("exclude", nil, "ignore files whose name or relative path matches one of these glob patterns")
+	flags.StringSlice("ext", nil, "only consider files with one of these extensions (e.g. sql.gz)")
+	flags.String("min-size", "", "only consider files of at least this size (e.g. 10M)")
+	flags.Bool("hidden", false, "include hidden files and directories")
+	flags.Bool("dirs", false, "treat every immediate subdirectory as one element (e.g. snapshots), removing freed ones recursively")
+	flags.String("group-by", "", "treat files in the same directory as one element if this regular expression extracts the same stem from their names (named group stem, first group, or whole match), e.g. '^[^.]+'")
+
+	// safety guards
+	addGuardFlags(flags)
+
+	// locking
+	addLockFlags(flags)
+
+	// disposal
+	flags.String("action", "delete", "what to do with freed elements: delete, trash (freedesktop.org trash), quarantine (move to --quarantine-dir), or archive (hard-link into --archive-dir, then remove)")
+	flags.String("quarantine-dir", "", "directory to move freed elements to for --action quarantine, keeping their relative paths")
+	flags.String("archive-dir", "", "directory to hard-link freed elements into for --action archive, keeping their relative paths")
+	flags.String("exec", "", "dispose of every freed element by running this command, with placeholders {} or {path}, {name}, {time}, and {tags} (e.g. 'rclone deletefile remote:db/{name}')")
+	flags.String("exec-batch", "", "dispose of the freed elements by running this command for as many elements as possible, ending in + (e.g. 'rm -- {} +')")
 }
 
-func runRoot(cmd *cobra.Command, args []string) {
+// addLockFlags defines the flags for locking target directories, see acquireLock.
+func addLockFlags(flags *pflag.FlagSet) {
+	flags.String("lock", "fail", "lock the target against concurrent runs: fail (if locked by another process), wait (until the lock is released or --lock-timeout passes), or off")
+	flags.Duration("lock-timeout", 0, "maximum time to wait for a lock with --lock wait, 0 waits forever")
+	flags.String("lock-dir", "", "directory for lock files (e.g. /run/keep) instead of a "+lockFileName+" file in the target")
+}
+
+func runRoot(cmd *cobra.Command, args []string) error {
+	cmd.SilenceUsage = true
+	err := applyEnvironment(cmd.Flags())
+	if err != nil {
+		return withExitCode(exitUsage, err)
+	}
 	env, err := parseEnvRoot(cmd, args)
 	if err != nil {
-		fmt.Fprintln(os.Stderr, err)
-		os.Exit(2)
+		return withExitCode(exitUsage, err)
 	}
 
-	now := time.Now()
-	reqs := keep.NewRequirementsFromString(env.Requirements)
-	fmt.Println(reqs)
-
-	if env.PrintRequirementsOnly {
-		os.Exit(0)
+	removed, err := runEnvRoot(env)
+	if err != nil {
+		return err
+	}
+	if removed == 0 && env.DetailedExitCodes && !env.PrintRequirementsOnly {
+		return withExitCode(exitNothingRemoved, errNothingRemoved)
 	}
+	return nil
+}
 
-	jh := keep.NewDefaultJailhouse[keep.File]()
+// errNothingRemoved is returned with --detailed-exit-codes if there were no free elements.
+var errNothingRemoved = errors.New("nothing to remove")
 
-	// file selection
-	wd, err := os.Getwd()
+// runEnvRoot prunes all target directories of env and returns the number of free elements disposed of (or
+// printed, or listed in a dry run). Failing disposals do not stop the other targets, all other errors do.
+func runEnvRoot(env EnvRoot) (int, error) {
+	out, err := newOutputWriter(env.Output, os.Stdout)
 	if err != nil {
-		log.Fatalf("Error reading current directory: %v", err)
+		return 0, withExitCode(exitUsage, err)
 	}
-	files, err := os.ReadDir(wd)
+
+	// human-readable output must not mix with machine-readable output
+	info := io.Writer(os.Stdout)
+	if !out.IsText() || env.Print0 {
+		info = os.Stderr
+	}
+
+	if env.Job != "" {
+		fmt.Fprintf(info, "\n=== %s ===\n", env.Job)
+	}
+
+	now := time.Now()
+	reqs, err := parseRequirements(env.Requirements)
 	if err != nil {
-		log.Fatalf("Error reading directory contents: %v", err)
+		return 0, err
 	}
+	fmt.Fprintln(info, reqs)
 
-	for _, file := range files {
-		if file.IsDir() {
+	if env.PrintRequirementsOnly {
+		return 0, nil
+	}
+
+	var (
+		removed  int
+		failures []string
+	)
+	for _, path := range env.Paths {
+		targetRemoved, err := runTarget(env, reqs, now, path, out, info)
+		removed += targetRemoved
+		if exitCode(err) == exitPartialFailure {
+			failures = append(failures, err.Error())
 			continue
 		}
-		filename := file.Name()
-		t, err := times.Stat(filename)
 		if err != nil {
-			log.Fatal(err.Error())
+			return removed, err
 		}
+	}
+	if len(failures) > 0 {
+		return removed, withExitCode(exitPartialFailure, errors.New(strings.Join(failures, "\n")))
+	}
+	return removed, nil
+}
 
-		jh.AddElements(keep.File{
-			Filename: filename,
-			Time:     t.BirthTime(),
-		})
+// guardAbort reports the violations and returns an error carrying exitGuard, nil if there are none.
+func guardAbort(violations []guardViolation) error {
+	if len(violations) == 0 {
+		return nil
+	}
+	for _, violation := range violations {
+		fmt.Fprintf(os.Stderr, "Aborting, %s\n", violation)
 	}
+	return withExitCode(exitGuard, errors.New("nothing has been removed, use --override-guard with the guard name to proceed anyway"))
+}
 
-	// apply requirements to find which files to keep and which to delete
-	jh.ApplyRequirementsForDate(*reqs, now)
+// runTarget applies the requirements to the files in the directory root and removes the free ones. Results are
+// written to out, everything else to info. It returns the number of free elements disposed of; if some of them
+// failed, the error lists them and carries exitPartialFailure.
+func runTarget(env EnvRoot, reqs *keep.Requirements, now time.Time, root string, out *outputWriter, info io.Writer) (int, error) {
+	if len(env.Paths) > 1 {
+		fmt.Fprintf(info, "\n== %s ==\n", root)
+	}
+
+	// only one process may modify a target at a time
+	if env.Lock != "off" && !env.DryRun && !env.Print0 {
+		path, err := lockFilePath(root, env.LockDir)
+		if err != nil {
+			return 0, withExitCode(exitUsage, err)
+		}
+		lock, err := acquireLock(path, env.Lock, env.LockTimeout)
+		if err != nil {
+			return 0, withExitCode(exitLocked, err)
+		}
+		defer lock.Release()
+	}
+
+	jh, reasons, err := evaluateTarget(env, reqs, now, root, info)
+	if err != nil {
+		return 0, err
+	}
 
 	k := jh.KeptElements()
-	fmt.Printf("\nKeeping %d files:\n", len(k))
+	fmt.Fprintf(info, "\nKeeping %d files:\n", len(k))
 	for _, keepElement := range k {
 		tags := keepElement.GetTags()
 		tagStrings := make([]string, len(tags))
 		for i, tag := range tags {
 			tagStrings[i] = tag.String()
 		}
-		fmt.Printf("%s [%s]\n", keepElement.TimeResource.Filename, strings.Join(tagStrings, ", "))
+		fmt.Fprintf(info, "%s [%s]\n", displayPath(keepElement.TimeResource), strings.Join(tagStrings, ", "))
+	}
+
+	coverage := jh.Report()
+	fmt.Fprintln(info, "\nCoverage:")
+	for _, report := range coverage {
+		fmt.Fprintln(info, report)
+	}
+
+	err = writeReports(jh, env.Reports, info)
+	if err != nil {
+		return 0, err
 	}
 
 	r := jh.FreeElements()
-	if len(r) > 0 {
-		fmt.Printf("\nRemoving %d files:\n", len(r))
+	err = out.Write(outputSummary{
+		Job:           env.Job,
+		Target:        root,
+		ReferenceDate: now,
+		Requirements:  *reqs,
+		Elements:      len(jh.Elements()),
+		Kept:          len(k),
+		Free:          len(r),
+		Coverage:      newOutputCoverage(coverage),
+	}, newOutputElements(jh, reasons))
+	if err != nil {
+		return 0, err
+	}
+	err = guardAbort(checkGuards(env.Guards, jh.Elements(), jh.GetAnchor(), now))
+	if err != nil {
+		return 0, err
+	}
+
+	if env.Print0 {
 		for _, keepElement := range r {
-			fmt.Println(keepElement.TimeResource.Filename)
+			for _, path := range keepElement.TimeResource.Paths() {
+				fmt.Print(path, "\x00")
+			}
 		}
+		return len(r), nil
+	}
 
-		doRemove := env.Force
-		if !env.Force {
-			reader := bufio.NewReader(os.Stdin)
+	if len(r) == 0 && !env.Review {
+		return 0, nil
+	}
 
-			fmt.Printf("\nRemove %d files as listed above? (Y/n) ", len(r))
-			input, err := reader.ReadString('\n')
-			if err != nil {
-				fmt.Println("Error reading input:", err)
-				return
-			}
+	if len(r) > 0 {
+		fmt.Fprintf(info, "\nRemoving %d files:\n", len(r))
+		printRemovals(info, r, reasons)
+	}
 
-			input = input[:len(input)-1] // Remove newline character from the input
+	if env.Review {
+		r, err = reviewElements(root, jh.Elements(), reasons)
+		if err != nil {
+			return 0, err
+		}
+		if len(r) == 0 {
+			fmt.Fprintln(info, "\nNothing to remove after review.")
+			return 0, nil
+		}
+		fmt.Fprintf(info, "\nRemoving %d files after review:\n", len(r))
+		printRemovals(info, r, reasons)
 
-			doRemove = input == "y" || input == "j" || input == ""
+		// the review may have freed elements the requirements keep, like the newest one
+		err = guardAbort(checkRemovalGuards(env.Guards, jh.Elements(), r, jh.GetAnchor(), now))
+		if err != nil {
+			return 0, err
 		}
-		if doRemove {
+	}
+
+	if !env.Force && !env.DryRun {
+		err = confirmRemoval(info, len(r), env.ConfirmThreshold)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	var audit *auditLog
+	if !env.DryRun {
+		audit, err = startAuditLog(env.AuditLog, env, root, *reqs, now)
+		if err != nil {
+			return 0, err
+		}
+		defer audit.Close()
+	}
+
+	if env.Exec != nil {
+		// the command is run for every file of a group
+		r = expandCompanions(r)
+		sizes := make(map[*keep.JailhouseTimeResource[fileElement]]int64)
+		if audit != nil {
 			for _, keepElement := range r {
-				f := keepElement.TimeResource.Filename
-				if env.DryRun {
-					fmt.Printf("[DRY-RUN] Would be deleting %s...\n", f)
-				} else {
-					err = os.Remove(f)
-					if err != nil {
-						fmt.Fprintln(os.Stderr, err)
-						os.Exit(4)
-					}
-				}
+				sizes[keepElement] = elementSize(keepElement.TimeResource.Filename, env.Dirs)
 			}
+		}
+		failed := 0
+		err = runExec(env.Exec, r, env.DryRun, info, func(element *keep.JailhouseTimeResource[fileElement], err error) {
+			if auditErr := audit.Removed(element, sizes[element], err); auditErr != nil {
+				fmt.Fprintln(os.Stderr, auditErr)
+			}
+			if err != nil {
+				failed++
+			}
+		})
+		if err != nil {
+			return len(r) - failed, withExitCode(exitPartialFailure, fmt.Errorf("%s: %w", root, err))
+		}
+		if !env.DryRun {
+			fmt.Fprintf(info, "disposed of %d files\n", len(r))
+		}
+		return len(r), nil
+	}
 
-			if !env.DryRun {
-				fmt.Printf("deleted %d files\n", len(r))
+	// keep going on failures, so one broken element does not hold back the others
+	failures := make([]string, 0)
+	for _, keepElement := range r {
+		f := keepElement.TimeResource.Filename
+		if env.DryRun {
+			fmt.Fprintf(info, "[DRY-RUN] Would be %s %s...\n", disposalActions[env.Action], displayPath(keepElement.TimeResource))
+			continue
+		}
+
+		var size int64
+		if audit != nil {
+			size = filesSize(keepElement.TimeResource, env.Dirs)
+		}
+		err = disposeFile(env.Disposal, root, keepElement.TimeResource, env.Dirs)
+		if auditErr := audit.Removed(keepElement, size, err); auditErr != nil {
+			fmt.Fprintln(os.Stderr, auditErr)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			failures = append(failures, f)
+		}
+	}
+
+	if env.DryRun {
+		return len(r), nil
+	}
+	fmt.Fprintf(info, "%s %d files\n", pastTense[env.Action], len(r)-len(failures))
+	if len(failures) > 0 {
+		return len(r) - len(failures), withExitCode(exitPartialFailure, fmt.Errorf("%s: failed to remove %d of %d files:\n%s", root, len(failures), len(r), strings.Join(failures, "\n")))
+	}
+	return len(r), nil
+}
+
+// printRemovals lists the elements to remove, with their reason if known.
+func printRemovals[T prunable](w io.Writer, elements []*keep.JailhouseTimeResource[T], reasons *reasonRecorder[T]) {
+	for _, element := range elements {
+		if reason := reasons.Reason(element); reason != "" {
+			fmt.Fprintf(w, "%s (%s)\n", element.TimeResource, reason)
+			continue
+		}
+		fmt.Fprintln(w, element.TimeResource)
+	}
+}
+
+// evaluateTarget selects the files in the directory root and applies the requirements to them for now. With
+// --reasons, the returned reasonRecorder explains the result.
+func evaluateTarget(env EnvRoot, reqs *keep.Requirements, now time.Time, root string, info io.Writer) (*keep.Jailhouse[fileElement], *reasonRecorder[fileElement], error) {
+	jh, err := newJailhouse[fileElement](env.Anchor, env.AnchorEpoch)
+	if err != nil {
+		return nil, nil, err
+	}
+	var reasons *reasonRecorder[fileElement]
+	if env.Reasons {
+		reasons = newReasonRecorder(jh, now)
+	}
+
+	// file selection
+	var files []scannedFile
+	if env.Dirs {
+		files, err = scanDirs(root, env.Filter)
+	} else {
+		files, err = scanFiles(root, env.Filter)
+	}
+	if err != nil {
+		return nil, nil, withExitCode(exitScan, fmt.Errorf("reading directory contents: %w", err))
+	}
+
+	elements := make([]fileElement, 0, len(files))
+	for _, file := range files {
+		if file.RelPath == lockFileName {
+			continue
+		}
+		t, source, err := fileTime(file, env.TimeSources, env.TimeExtractor)
+		if err != nil {
+			if env.Unmatched == "report" {
+				fmt.Fprintf(os.Stderr, "Skipping %s: %v\n", file.Path, err)
 			}
+			continue
+		}
+		if env.Verbose {
+			fmt.Fprintf(info, "%s: %s (%s)\n", file.Path, t.Format(time.RFC3339), source)
+		}
+
+		elements = append(elements, fileElement{
+			Filename: file.Path,
+			Time:     t,
+		})
+	}
+	jh.AddElements(groupCompanions(elements, env.GroupBy, env.Dirs)...)
+
+	// apply requirements to find which files to keep and which to delete
+	err = applyRequirements(jh, reqs, now)
+	if err != nil {
+		return nil, nil, err
+	}
+	return jh, reasons, nil
+}
+
+// prunable is an element keep prunes, like a file or a git ref. String returns its name for listings.
+type prunable interface {
+	keep.TimeResource
+	fmt.Stringer
+}
+
+// parseRequirements parses the requirements set by --requirements. Unknown text and requirements keeping nothing are
+// usage errors.
+func parseRequirements(source string) (*keep.Requirements, error) {
+	reqs, err := keep.ParseRequirements(source)
+	if err != nil {
+		return nil, withExitCode(exitUsage, err)
+	}
+	if reqs.IsEmpty() {
+		return nil, withExitCode(exitUsage, fmt.Errorf("requirements %q keep nothing", source))
+	}
+	return reqs, nil
+}
+
+// newJailhouse creates a Jailhouse anchored as set by --anchor and --anchor-epoch.
+func newJailhouse[T prunable](anchorName, anchorEpoch string) (*keep.Jailhouse[T], error) {
+	anchor, err := keep.ParseAnchor(anchorName)
+	if err != nil {
+		return nil, withExitCode(exitUsage, err)
+	}
+	jh := keep.NewDefaultJailhouse[T]().SetAnchor(anchor)
+	if anchorEpoch != "" {
+		epoch, err := time.ParseInLocation("2006-01-02", anchorEpoch, time.Local)
+		if err != nil {
+			return nil, withExitCode(exitUsage, err)
 		}
+		jh.SetEpoch(epoch)
+	}
+	return jh, nil
+}
+
+// applyRequirements applies the requirements to the elements of jh for now, until interrupted.
+func applyRequirements[T prunable](jh *keep.Jailhouse[T], reqs *keep.Requirements, now time.Time) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	err := jh.ApplyRequirementsForDateContext(ctx, *reqs, now)
+	if errors.Is(err, context.Canceled) {
+		return withExitCode(exitAborted, err)
+	}
+	if err != nil {
+		return withExitCode(exitUsage, err)
 	}
+	return nil
 }
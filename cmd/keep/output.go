@@ -0,0 +1,184 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/jojomi/keep"
+)
+
+// outputElement is the machine-readable description of a single element, see --output.
+type outputElement struct {
+	Path   string    `json:"path"`
+	Time   time.Time `json:"time"`
+	Status string    `json:"status"`
+	Tags   []string  `json:"tags"`
+	Reason string    `json:"reason,omitempty"`
+	// Companions are the other files of the group with --group-by, not part of csv and tsv output.
+	Companions []string `json:"companions,omitempty"`
+}
+
+// outputSummary is the machine-readable summary of a target directory, see --output.
+type outputSummary struct {
+	Job           string            `json:"job,omitempty"`
+	Target        string            `json:"target"`
+	ReferenceDate time.Time         `json:"referenceDate"`
+	Requirements  keep.Requirements `json:"requirements"`
+	Elements      int               `json:"elements"`
+	Kept          int               `json:"kept"`
+	Free          int               `json:"free"`
+	// Coverage reports how well every level of the requirements is met, see newOutputCoverage.
+	Coverage []keep.LevelReport[fileElement] `json:"coverage"`
+}
+
+// outputWriter writes the results of all target directories in one of the formats of --output.
+type outputWriter struct {
+	format        string
+	w             io.Writer
+	headerWritten bool
+}
+
+func newOutputWriter(format string, w io.Writer) (*outputWriter, error) {
+	switch format {
+	case "text", "json", "ndjson", "csv", "tsv":
+		return &outputWriter{
+			format: format,
+			w:      w,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown output format %q, try [text, json, ndjson, csv, tsv]", format)
+	}
+}
+
+// IsText is true for the human-readable text output, which is printed along the way instead of by the outputWriter.
+func (x *outputWriter) IsText() bool {
+	return x.format == "text"
+}
+
+// Write writes the summary and elements of one target directory. json writes one document per target, ndjson one
+// line per element followed by one for the summary, csv and tsv one row per element below a common header.
+func (x *outputWriter) Write(summary outputSummary, elements []outputElement) error {
+	switch x.format {
+	case "json":
+		encoder := json.NewEncoder(x.w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(struct {
+			Summary  outputSummary   `json:"summary"`
+			Elements []outputElement `json:"elements"`
+		}{summary, elements})
+	case "ndjson":
+		encoder := json.NewEncoder(x.w)
+		for _, element := range elements {
+			err := encoder.Encode(struct {
+				Type string `json:"type"`
+				outputElement
+			}{"element", element})
+			if err != nil {
+				return err
+			}
+		}
+		return encoder.Encode(struct {
+			Type string `json:"type"`
+			outputSummary
+		}{"summary", summary})
+	case "csv", "tsv":
+		writer := csv.NewWriter(x.w)
+		if x.format == "tsv" {
+			writer.Comma = '\t'
+		}
+		if !x.headerWritten {
+			x.headerWritten = true
+			err := writer.Write([]string{"path", "time", "status", "tags", "reason"})
+			if err != nil {
+				return err
+			}
+		}
+		for _, element := range elements {
+			err := writer.Write([]string{element.Path, element.Time.Format(time.RFC3339), element.Status, strings.Join(element.Tags, ","), element.Reason})
+			if err != nil {
+				return err
+			}
+		}
+		writer.Flush()
+		return writer.Error()
+	default:
+		return nil
+	}
+}
+
+// newOutputElements describes all elements of jh, youngest first, with reasons if available.
+func newOutputElements(jh *keep.Jailhouse[fileElement], reasons *reasonRecorder[fileElement]) []outputElement {
+	elements := make([]outputElement, 0)
+	for _, element := range jh.Elements() {
+		status := "kept"
+		if element.IsFree() {
+			status = "free"
+		}
+		tags := make([]string, len(element.GetTags()))
+		for i, tag := range element.GetTags() {
+			tags[i] = tag.String()
+		}
+		elements = append(elements, outputElement{
+			Path:       element.TimeResource.Filename,
+			Time:       element.GetTime(),
+			Status:     status,
+			Tags:       tags,
+			Reason:     reasons.Reason(element),
+			Companions: element.TimeResource.Companions,
+		})
+	}
+	return elements
+}
+
+// newOutputCoverage returns the reports without their oldest element, whose time is part of the report already.
+func newOutputCoverage(reports []keep.LevelReport[fileElement]) []keep.LevelReport[fileElement] {
+	coverage := make([]keep.LevelReport[fileElement], len(reports))
+	for i, report := range reports {
+		report.OldestElement = nil
+		coverage[i] = report
+	}
+	return coverage
+}
+
+// reasonRecorder collects why elements are kept or free while requirements are applied, see --reasons.
+type reasonRecorder[T prunable] struct {
+	referenceDate time.Time
+	skips         map[*keep.JailhouseTimeResource[T]][]string
+}
+
+// newReasonRecorder registers a reasonRecorder with jh, which is going to be evaluated for referenceDate.
+func newReasonRecorder[T prunable](jh *keep.Jailhouse[T], referenceDate time.Time) *reasonRecorder[T] {
+	x := &reasonRecorder[T]{
+		referenceDate: referenceDate,
+		skips:         make(map[*keep.JailhouseTimeResource[T]][]string),
+	}
+	jh.OnSkip(func(element, neighbour *keep.JailhouseTimeResource[T], level keep.TimeRange) {
+		x.skips[element] = append(x.skips[element], fmt.Sprintf("%s represented by %s", level, neighbour.TimeResource))
+	})
+	return x
+}
+
+// Reason describes why the element is kept or free, it is empty for a nil reasonRecorder.
+func (x *reasonRecorder[T]) Reason(element *keep.JailhouseTimeResource[T]) string {
+	if x == nil {
+		return ""
+	}
+	if !element.IsFree() {
+		tags := make([]string, len(element.GetTags()))
+		for i, tag := range element.GetTags() {
+			tags[i] = tag.String()
+		}
+		return "kept as " + strings.Join(tags, ", ")
+	}
+	if element.GetTime().After(x.referenceDate) {
+		return "in the future"
+	}
+	if skips := x.skips[element]; len(skips) > 0 {
+		return strings.Join(skips, "; ")
+	}
+	return "not needed by any level"
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jojomi/keep"
+	"github.com/stretchr/testify/assert"
+)
+
+// outputJailhouse evaluates four daily backups and one from the future for "1 last, 1 week".
+func outputJailhouse(t *testing.T, anchor keep.Anchor, withReasons bool) (*keep.Jailhouse[fileElement], *reasonRecorder[fileElement]) {
+	now := time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC)
+	jh := keep.NewDefaultJailhouse[fileElement]().SetAnchor(anchor)
+	var reasons *reasonRecorder[fileElement]
+	if withReasons {
+		reasons = newReasonRecorder(jh, now)
+	}
+	for i := 1; i <= 4; i++ {
+		jh.AddElements(fileElement{
+			Filename: "db/" + time.Date(2024, 3, i, 2, 0, 0, 0, time.UTC).Format("2006-01-02") + ".sql",
+			Time:     time.Date(2024, 3, i, 2, 0, 0, 0, time.UTC),
+		})
+	}
+	jh.AddElements(fileElement{Filename: "db/future.sql", Time: now.Add(time.Hour)})
+	assert.NoError(t, jh.ApplyRequirementsForDateContext(context.Background(), *keep.NewRequirementsFromString("1 last, 1 week"), now))
+	return jh, reasons
+}
+
+func TestNewOutputWriter(t *testing.T) {
+	for _, format := range []string{"text", "json", "ndjson", "csv", "tsv"} {
+		_, err := newOutputWriter(format, &bytes.Buffer{})
+		assert.NoError(t, err, format)
+	}
+	_, err := newOutputWriter("xml", &bytes.Buffer{})
+	assert.Error(t, err)
+}
+
+func TestOutputWriter_Write(t *testing.T) {
+	jh, reasons := outputJailhouse(t, keep.AnchorYoungest, true)
+	summary := outputSummary{
+		Target:       "db",
+		Requirements: *keep.NewRequirementsFromString("1 last, 1 week"),
+		Elements:     5,
+		Kept:         2,
+		Free:         3,
+		Coverage:     newOutputCoverage(jh.Report()),
+	}
+	elements := newOutputElements(jh, reasons)
+
+	tests := []struct {
+		format string
+		check  func(t *testing.T, output string)
+	}{
+		{
+			format: "text",
+			check: func(t *testing.T, output string) {
+				assert.Empty(t, output)
+			},
+		},
+		{
+			format: "json",
+			check: func(t *testing.T, output string) {
+				var decoded struct {
+					Summary  map[string]any   `json:"summary"`
+					Elements []map[string]any `json:"elements"`
+				}
+				assert.NoError(t, json.Unmarshal([]byte(output), &decoded))
+				assert.Equal(t, map[string]any{"LAST": 1.0, "WEEK": 1.0}, decoded.Summary["requirements"])
+				assert.Len(t, decoded.Elements, 5)
+				coverage := decoded.Summary["coverage"].([]any)
+				assert.Len(t, coverage, 2)
+				assert.Equal(t, "LAST", coverage[0].(map[string]any)["level"])
+				assert.Equal(t, 0.0, coverage[0].(map[string]any)["spanSeconds"])
+				assert.NotContains(t, coverage[0], "oldestElement")
+			},
+		},
+		{
+			format: "ndjson",
+			check: func(t *testing.T, output string) {
+				lines := strings.Split(strings.TrimSpace(output), "\n")
+				assert.Len(t, lines, 6)
+				assert.Contains(t, lines[0], `"type":"element"`)
+				assert.Contains(t, lines[5], `"type":"summary"`)
+				assert.Contains(t, lines[5], `"free":3`)
+			},
+		},
+		{
+			format: "csv",
+			check: func(t *testing.T, output string) {
+				lines := strings.Split(strings.TrimSpace(output), "\n")
+				// the header is only written once for all targets
+				assert.Len(t, lines, 11)
+				assert.Equal(t, "path,time,status,tags,reason", lines[0])
+				assert.Equal(t, "db/2024-03-04.sql,2024-03-04T02:00:00Z,kept,LAST-1,kept as LAST-1", lines[2])
+			},
+		},
+		{
+			format: "tsv",
+			check: func(t *testing.T, output string) {
+				lines := strings.Split(strings.TrimSpace(output), "\n")
+				assert.Equal(t, "path\ttime\tstatus\ttags\treason", lines[0])
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			var buf bytes.Buffer
+			out, err := newOutputWriter(tt.format, &buf)
+			assert.NoError(t, err)
+			assert.NoError(t, out.Write(summary, elements))
+			if tt.format == "csv" {
+				assert.NoError(t, out.Write(summary, elements))
+			}
+			tt.check(t, buf.String())
+		})
+	}
+}
+
+func TestReasonRecorder(t *testing.T) {
+	jh, reasons := outputJailhouse(t, keep.AnchorOldest, true)
+	got := make(map[string]string)
+	for _, element := range jh.Elements() {
+		got[element.TimeResource.Filename] = reasons.Reason(element)
+	}
+	assert.Equal(t, map[string]string{
+		"db/future.sql":     "in the future",
+		"db/2024-03-04.sql": "kept as LAST-1",
+		"db/2024-03-03.sql": "WEEK represented by db/2024-03-02.sql",
+		"db/2024-03-02.sql": "WEEK represented by db/2024-03-01.sql",
+		"db/2024-03-01.sql": "kept as WEEK-1",
+	}, got)
+
+	jh, reasons = outputJailhouse(t, keep.AnchorYoungest, true)
+	assert.Equal(t, "not needed by any level", reasons.Reason(jh.Elements()[4]))
+
+	// without --reasons there is no reason
+	jh, reasons = outputJailhouse(t, keep.AnchorYoungest, false)
+	assert.Empty(t, reasons.Reason(jh.Elements()[0]))
+}
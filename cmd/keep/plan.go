@@ -0,0 +1,291 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/jojomi/keep"
+	"github.com/spf13/cobra"
+)
+
+// planVersion is the version of the plan file format written by `keep plan`.
+const planVersion = 1
+
+// plan lists the elements `keep apply` is going to remove, so the removal can be reviewed beforehand.
+type plan struct {
+	Version       int               `json:"version"`
+	Created       time.Time         `json:"created"`
+	Host          string            `json:"host"`
+	User          string            `json:"user"`
+	Requirements  keep.Requirements `json:"requirements"`
+	ReferenceDate time.Time         `json:"referenceDate"`
+	Targets       []planTarget      `json:"targets"`
+}
+
+// planTarget lists the elements to remove from one target directory and how to dispose of them.
+type planTarget struct {
+	Job           string        `json:"job,omitempty"`
+	Target        string        `json:"target"`
+	Dirs          bool          `json:"dirs,omitempty"`
+	Action        string        `json:"action"`
+	QuarantineDir string        `json:"quarantineDir,omitempty"`
+	ArchiveDir    string        `json:"archiveDir,omitempty"`
+	Exec          string        `json:"exec,omitempty"`
+	ExecBatch     bool          `json:"execBatch,omitempty"`
+	Elements      []planElement `json:"elements"`
+}
+
+// planElement is an element to remove. Its fingerprint covers the content, so changes after planning are detected.
+type planElement struct {
+	Path        string    `json:"path"`
+	Size        int64     `json:"size"`
+	ModTime     time.Time `json:"mtime"`
+	Time        time.Time `json:"time"`
+	Fingerprint string    `json:"fingerprint"`
+	// Companions are the other files of the group with --group-by, removed together with the element.
+	Companions []planElement `json:"companions,omitempty"`
+}
+
+func getPlanCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "plan [path...]",
+		Short: "write a plan file listing the elements to remove, to be reviewed and executed by keep apply",
+		Args:  cobra.ArbitraryArgs,
+		RunE:  runPlan,
+	}
+
+	flags := cmd.Flags()
+	addTargetFlags(flags)
+	flags.String("out", "-", "plan file to write, - for stdout")
+
+	return cmd
+}
+
+func runPlan(cmd *cobra.Command, args []string) error {
+	cmd.SilenceUsage = true
+	err := applyEnvironment(cmd.Flags())
+	if err != nil {
+		return withExitCode(exitUsage, err)
+	}
+	env, err := parseEnvRoot(cmd, args)
+	if err != nil {
+		return withExitCode(exitUsage, err)
+	}
+	out, err := cmd.Flags().GetString("out")
+	if err != nil {
+		return withExitCode(exitUsage, err)
+	}
+
+	p, err := newPlan(env, time.Now(), os.Stderr)
+	if err != nil {
+		return err
+	}
+
+	err = writePlan(p, out)
+	if err != nil {
+		return err
+	}
+	count := 0
+	for _, target := range p.Targets {
+		count += len(target.Elements)
+	}
+	if out != "-" {
+		fmt.Fprintf(os.Stderr, "Planned removal of %d elements, written to %s\n", count, out)
+	}
+	for _, target := range p.Targets {
+		if target.Action == "exec" {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", target.Target, target.describeExec())
+		}
+	}
+	if count == 0 && env.DetailedExitCodes {
+		return withExitCode(exitNothingRemoved, errNothingRemoved)
+	}
+	return nil
+}
+
+// describeExec returns the command template of an exec target and how it is invoked, for review before applying.
+func (x planTarget) describeExec() string {
+	if x.ExecBatch {
+		return fmt.Sprintf("exec: %s (batch, as few invocations as possible)", x.Exec)
+	}
+	return fmt.Sprintf("exec: %s (once per element)", x.Exec)
+}
+
+// guardError is returned by newPlan if a guard fired for a target.
+type guardError struct {
+	target     string
+	violations []guardViolation
+}
+
+func (x guardError) Error() string {
+	lines := make([]string, 0, len(x.violations)+1)
+	for _, violation := range x.violations {
+		lines = append(lines, fmt.Sprintf("Aborting plan for %s, %s", x.target, violation))
+	}
+	lines = append(lines, "Nothing has been planned. Use --override-guard with the guard name to proceed anyway.")
+	return strings.Join(lines, "\n")
+}
+
+// newPlan evaluates all targets of env for now and creates a plan removing their free elements.
+func newPlan(env EnvRoot, now time.Time, info io.Writer) (*plan, error) {
+	host, _ := os.Hostname()
+	reqs, err := parseRequirements(env.Requirements)
+	if err != nil {
+		return nil, err
+	}
+	p := &plan{
+		Version:       planVersion,
+		Created:       now,
+		Host:          host,
+		User:          currentUser(),
+		Requirements:  *reqs,
+		ReferenceDate: now,
+		Targets:       make([]planTarget, 0, len(env.Paths)),
+	}
+
+	for _, root := range env.Paths {
+		jh, _, err := evaluateTarget(env, reqs, now, root, info)
+		if err != nil {
+			return nil, err
+		}
+		violations := checkGuards(env.Guards, jh.Elements(), jh.GetAnchor(), now)
+		if len(violations) > 0 {
+			return nil, withExitCode(exitGuard, guardError{root, violations})
+		}
+
+		absRoot, err := filepath.Abs(root)
+		if err != nil {
+			return nil, withExitCode(exitScan, err)
+		}
+		target := planTarget{
+			Job:           env.Job,
+			Target:        absRoot,
+			Dirs:          env.Dirs,
+			Action:        env.Action,
+			QuarantineDir: env.QuarantineDir,
+			ArchiveDir:    env.ArchiveDir,
+			Elements:      make([]planElement, 0),
+		}
+		if env.Exec != nil {
+			target.Action = "exec"
+			target.Exec = env.Exec.command
+			target.ExecBatch = env.Exec.batch
+		}
+		for _, element := range jh.FreeElements() {
+			planned, err := newPlanElement(element.TimeResource.Filename, element.GetTime(), env.Dirs)
+			if err != nil {
+				return nil, withExitCode(exitScan, err)
+			}
+			for _, companion := range element.TimeResource.Companions {
+				plannedCompanion, err := newPlanElement(companion, element.GetTime(), env.Dirs)
+				if err != nil {
+					return nil, withExitCode(exitScan, err)
+				}
+				planned.Companions = append(planned.Companions, plannedCompanion)
+			}
+			target.Elements = append(target.Elements, planned)
+		}
+		p.Targets = append(p.Targets, target)
+	}
+	return p, nil
+}
+
+func newPlanElement(path string, t time.Time, dir bool) (planElement, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return planElement{}, err
+	}
+	info, err := os.Lstat(absPath)
+	if err != nil {
+		return planElement{}, err
+	}
+	sum, err := fingerprint(absPath)
+	if err != nil {
+		return planElement{}, err
+	}
+	return planElement{
+		Path:        absPath,
+		Size:        elementSize(absPath, dir),
+		ModTime:     info.ModTime(),
+		Time:        t,
+		Fingerprint: sum,
+	}, nil
+}
+
+// fingerprint hashes the content of the file at path, or the names, types and contents of all files below it for
+// directories. Symbolic links are not followed, their targets are hashed instead.
+func fingerprint(path string) (string, error) {
+	hash := sha256.New()
+	err := filepath.WalkDir(path, func(current string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(path, current)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(hash, "%s\x00%s\x00", filepath.ToSlash(rel), d.Type())
+
+		switch {
+		case d.Type()&fs.ModeSymlink != 0:
+			link, err := os.Readlink(current)
+			if err != nil {
+				return err
+			}
+			_, err = io.WriteString(hash, link)
+			return err
+		case d.Type().IsRegular():
+			file, err := os.Open(current)
+			if err != nil {
+				return err
+			}
+			defer file.Close()
+			_, err = io.Copy(hash, file)
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return "sha256:" + hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// writePlan writes the plan as indented JSON to path, - for stdout.
+func writePlan(p *plan, path string) error {
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+	if path == "-" {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// readPlan reads a plan written by writePlan.
+func readPlan(path string) (*plan, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var p plan
+	err = json.Unmarshal(data, &p)
+	if err != nil {
+		return nil, fmt.Errorf("reading plan %s: %w", path, err)
+	}
+	if p.Version != planVersion {
+		return nil, fmt.Errorf("unsupported plan version %d in %s, expected %d", p.Version, path, planVersion)
+	}
+	return &p, nil
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jojomi/keep"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFingerprint(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.MkdirAll(filepath.Join(dir, "snap", "sub"), 0o755))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "snap", "sub", "a"), []byte("a"), 0o644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "b"), []byte("b"), 0o644))
+
+	file, err := fingerprint(filepath.Join(dir, "b"))
+	assert.NoError(t, err)
+	snapshot, err := fingerprint(filepath.Join(dir, "snap"))
+	assert.NoError(t, err)
+	assert.NotEqual(t, file, snapshot)
+
+	// same content, same fingerprint
+	again, err := fingerprint(filepath.Join(dir, "snap"))
+	assert.NoError(t, err)
+	assert.Equal(t, snapshot, again)
+
+	// changes deep inside a directory are detected, renames too
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "snap", "sub", "a"), []byte("A"), 0o644))
+	changed, err := fingerprint(filepath.Join(dir, "snap"))
+	assert.NoError(t, err)
+	assert.NotEqual(t, snapshot, changed)
+	assert.NoError(t, os.Rename(filepath.Join(dir, "snap", "sub", "a"), filepath.Join(dir, "snap", "sub", "c")))
+	renamed, err := fingerprint(filepath.Join(dir, "snap"))
+	assert.NoError(t, err)
+	assert.NotEqual(t, changed, renamed)
+}
+
+func TestPlanApply(t *testing.T) {
+	dir := t.TempDir()
+	planPath := filepath.Join(t.TempDir(), "plan.json")
+	auditPath := filepath.Join(t.TempDir(), "audit.jsonl")
+	names := []string{"a.sql", "b.sql", "c.sql", "d.sql"}
+	for _, name := range names {
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(name), 0o644))
+	}
+
+	target := planTarget{
+		Target:   dir,
+		Action:   "delete",
+		Elements: make([]planElement, 0),
+	}
+	for i, name := range names {
+		element, err := newPlanElement(filepath.Join(dir, name), time.Date(2024, 3, i+1, 0, 0, 0, 0, time.UTC), false)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(5), element.Size)
+		target.Elements = append(target.Elements, element)
+	}
+	p := &plan{
+		Version:       planVersion,
+		Created:       time.Now().UTC().Truncate(time.Second),
+		Requirements:  *keep.NewRequirementsFromString("7 days"),
+		ReferenceDate: time.Now().UTC().Truncate(time.Second),
+		Targets:       []planTarget{target},
+	}
+	assert.NoError(t, writePlan(p, planPath))
+	read, err := readPlan(planPath)
+	assert.NoError(t, err)
+	assert.Equal(t, p.Requirements.String(), read.Requirements.String())
+	assert.True(t, p.Created.Equal(read.Created))
+	assert.Equal(t, len(target.Elements), len(read.Targets[0].Elements))
+	for i, element := range read.Targets[0].Elements {
+		assert.Equal(t, target.Elements[i].Path, element.Path)
+		assert.Equal(t, target.Elements[i].Fingerprint, element.Fingerprint)
+		assert.True(t, target.Elements[i].Time.Equal(element.Time))
+	}
+
+	// changes after planning
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "b.sql"), []byte("other"), 0o644))
+	assert.NoError(t, os.Remove(filepath.Join(dir, "c.sql")))
+
+	env := EnvApply{Plan: planPath, Lock: "off"}
+	env.DryRun = true
+	failed, err := applyPlanTarget(read, read.Targets[0], env, io.Discard)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, failed)
+	assert.FileExists(t, filepath.Join(dir, "a.sql"))
+
+	env.DryRun = false
+	env.AuditLog = auditPath
+	failed, err = applyPlanTarget(read, read.Targets[0], env, io.Discard)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, failed)
+	assert.NoFileExists(t, filepath.Join(dir, "a.sql"))
+	assert.FileExists(t, filepath.Join(dir, "b.sql"))
+	assert.NoFileExists(t, filepath.Join(dir, "d.sql"))
+
+	records, _, err := readAuditLog(auditPath, auditFilter{FailedOnly: true})
+	assert.NoError(t, err)
+	assert.Len(t, records, 2)
+}
+
+func TestPlanApply_Exec(t *testing.T) {
+	dir := t.TempDir()
+	auditPath := filepath.Join(t.TempDir(), "audit.jsonl")
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "a.sql"), []byte("a.sql"), 0o644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "a.sql.md5"), []byte("md5"), 0o644))
+
+	element, err := newPlanElement(filepath.Join(dir, "a.sql"), time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), false)
+	assert.NoError(t, err)
+	companion, err := newPlanElement(filepath.Join(dir, "a.sql.md5"), element.Time, false)
+	assert.NoError(t, err)
+	element.Companions = []planElement{companion}
+	target := planTarget{
+		Target:    dir,
+		Action:    "exec",
+		Exec:      "rm -- {} +",
+		ExecBatch: true,
+		Elements:  []planElement{element},
+	}
+	assert.Equal(t, "exec: rm -- {} + (batch, as few invocations as possible)", target.describeExec())
+	p := &plan{
+		Version:      planVersion,
+		Requirements: *keep.NewRequirementsFromString("7 days"),
+		Targets:      []planTarget{target},
+	}
+
+	failed, err := applyPlanTarget(p, target, EnvApply{Lock: "off", AuditLog: auditPath}, io.Discard)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, failed)
+	assert.NoFileExists(t, filepath.Join(dir, "a.sql"))
+
+	records, _, err := readAuditLog(auditPath, auditFilter{})
+	assert.NoError(t, err)
+	sizes := make(map[string]int64)
+	for _, record := range records {
+		if record.Size != nil {
+			sizes[record.Path] = *record.Size
+		}
+	}
+	assert.Equal(t, map[string]int64{element.Path: 5, companion.Path: 3}, sizes)
+}
+
+func TestReadPlan_Version(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "plan.json")
+	assert.NoError(t, os.WriteFile(path, []byte(`{"version": 99}`), 0o644))
+	_, err := readPlan(path)
+	assert.ErrorContains(t, err, "unsupported plan version 99")
+
+	assert.NoError(t, os.WriteFile(path, []byte(`{`), 0o644))
+	_, err = readPlan(path)
+	assert.Error(t, err)
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// stdinIsTerminal reports whether a user can answer prompts on stdin. /dev/null (as used by cron) is a character
+// device like a terminal, so it is ruled out explicitly.
+var stdinIsTerminal = func() bool {
+	info, err := os.Stdin.Stat()
+	if err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		return false
+	}
+	null, err := os.Stat(os.DevNull)
+	return err != nil || !os.SameFile(info, null)
+}
+
+// confirm asks question on w and reads the answer from stdin. It is true if the answer is one of answers, ignoring
+// case. Anything else, including an empty answer or the end of the input, means no.
+func confirm(w io.Writer, question string, answers ...string) (bool, error) {
+	fmt.Fprintf(w, "\n%s ", question)
+	input, err := stdin.ReadString('\n')
+	if errors.Is(err, io.EOF) {
+		fmt.Fprintln(w)
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	input = strings.TrimSpace(input)
+	for _, answer := range answers {
+		if strings.EqualFold(input, answer) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// confirmRemoval asks whether count elements should be removed. From threshold elements on (if not 0), "y" is not
+// enough, the count or "yes" has to be typed. Without a terminal on stdin it refuses right away. It returns nil if
+// the removal was confirmed, an error with exitAborted otherwise.
+func confirmRemoval(w io.Writer, count, threshold int) error {
+	if !stdinIsTerminal() {
+		return withExitCode(exitAborted, errors.New("not removing anything without confirmation, stdin is not a terminal, use --force or --yes"))
+	}
+
+	var (
+		ok  bool
+		err error
+	)
+	if threshold > 0 && count >= threshold {
+		ok, err = confirm(w, fmt.Sprintf("Remove %d files as listed above? Type %d or yes to confirm:", count, count), strconv.Itoa(count), "yes")
+	} else {
+		ok, err = confirm(w, fmt.Sprintf("Remove %d files as listed above? (y/N)", count), "y", "yes")
+	}
+	if err != nil {
+		return withExitCode(exitAborted, fmt.Errorf("reading input: %w", err))
+	}
+	if !ok {
+		return withExitCode(exitAborted, errors.New("aborted, nothing has been removed"))
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfirmRemoval(t *testing.T) {
+	tests := []struct {
+		name      string
+		terminal  bool
+		input     string
+		count     int
+		threshold int
+		want      bool
+	}{
+		{name: "yes", terminal: true, input: "y\n", count: 3, threshold: 20, want: true},
+		{name: "long yes", terminal: true, input: " YES \n", count: 3, threshold: 20, want: true},
+		{name: "empty is no", terminal: true, input: "\n", count: 3, threshold: 20, want: false},
+		{name: "no", terminal: true, input: "n\n", count: 3, threshold: 20, want: false},
+		{name: "j is no", terminal: true, input: "j\n", count: 3, threshold: 20, want: false},
+		{name: "end of input", terminal: true, input: "", count: 3, threshold: 20, want: false},
+		{name: "end of input without newline", terminal: true, input: "y", count: 3, threshold: 20, want: false},
+		{name: "large needs more than y", terminal: true, input: "y\n", count: 20, threshold: 20, want: false},
+		{name: "large count", terminal: true, input: "20\n", count: 20, threshold: 20, want: true},
+		{name: "large yes", terminal: true, input: "yes\n", count: 20, threshold: 20, want: true},
+		{name: "large wrong count", terminal: true, input: "21\n", count: 20, threshold: 20, want: false},
+		{name: "threshold disabled", terminal: true, input: "y\n", count: 500, threshold: 0, want: true},
+		{name: "no terminal", terminal: false, input: "y\n", count: 3, threshold: 20, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			previousStdin, previousTerminal := stdin, stdinIsTerminal
+			defer func() {
+				stdin, stdinIsTerminal = previousStdin, previousTerminal
+			}()
+			stdin = bufio.NewReader(strings.NewReader(tt.input))
+			stdinIsTerminal = func() bool { return tt.terminal }
+
+			err := confirmRemoval(io.Discard, tt.count, tt.threshold)
+			if tt.want {
+				assert.NoError(t, err)
+				return
+			}
+			assert.Equal(t, exitAborted, exitCode(err))
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// removeElement removes the file at path or, for directory elements, the whole directory tree. The element must be
+// inside root after resolving symbolic links, and directory elements must not be symbolic links themselves, so
+// nothing outside of root is ever removed.
+func removeElement(root, path string, dir bool) error {
+	err := ensureInside(root, path)
+	if err != nil {
+		return err
+	}
+	if !dir {
+		return os.Remove(path)
+	}
+
+	info, err := os.Lstat(path)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("refusing to remove %s: not a directory", path)
+	}
+	// RemoveAll removes symbolic links inside the tree, but never follows them
+	return os.RemoveAll(path)
+}
+
+// ensureInside returns an error unless path, with symbolic links in its parent resolved, is inside root.
+func ensureInside(root, path string) error {
+	resolvedRoot, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return err
+	}
+	resolvedParent, err := filepath.EvalSymlinks(filepath.Dir(path))
+	if err != nil {
+		return err
+	}
+	rel, err := filepath.Rel(resolvedRoot, filepath.Join(resolvedParent, filepath.Base(path)))
+	if err != nil {
+		return err
+	}
+	if rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return fmt.Errorf("refusing to remove %s: outside of %s", path, root)
+	}
+	return nil
+}
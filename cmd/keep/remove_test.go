@@ -0,0 +1,72 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// snapshotTree creates root/daily.N directories with files, a directory outside of root that snapshots link to,
+// and a symbolic link to that directory directly in root.
+func snapshotTree(t *testing.T) (root, outside string) {
+	base := t.TempDir()
+	root = filepath.Join(base, "snapshots")
+	outside = filepath.Join(base, "outside")
+
+	assert.NoError(t, os.MkdirAll(outside, 0o755))
+	assert.NoError(t, os.WriteFile(filepath.Join(outside, "precious"), []byte("x"), 0o644))
+	for _, name := range []string{"daily.0", "daily.1", ".sync"} {
+		dir := filepath.Join(root, name, "etc")
+		assert.NoError(t, os.MkdirAll(dir, 0o755))
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, "hosts"), []byte("x"), 0o644))
+	}
+	assert.NoError(t, os.Symlink(outside, filepath.Join(root, "daily.1", "link")))
+	assert.NoError(t, os.Symlink(outside, filepath.Join(root, "daily.2")))
+	assert.NoError(t, os.WriteFile(filepath.Join(root, "README"), []byte("x"), 0o644))
+	return root, outside
+}
+
+func TestScanDirs(t *testing.T) {
+	root, _ := snapshotTree(t)
+
+	dirs, err := scanDirs(root, fileFilter{})
+	assert.NoError(t, err)
+	assert.Equal(t, []scannedFile{
+		{Path: filepath.Join(root, "daily.0"), RelPath: "daily.0"},
+		{Path: filepath.Join(root, "daily.1"), RelPath: "daily.1"},
+	}, dirs)
+
+	dirs, err = scanDirs(root, fileFilter{Hidden: true, Exclude: []string{"daily.0"}})
+	assert.NoError(t, err)
+	assert.Equal(t, []scannedFile{
+		{Path: filepath.Join(root, ".sync"), RelPath: ".sync"},
+		{Path: filepath.Join(root, "daily.1"), RelPath: "daily.1"},
+	}, dirs)
+}
+
+func TestRemoveElement(t *testing.T) {
+	root, outside := snapshotTree(t)
+
+	// a snapshot containing a link to the outside is removed, the link target is not touched
+	assert.NoError(t, removeElement(root, filepath.Join(root, "daily.1"), true))
+	assert.NoDirExists(t, filepath.Join(root, "daily.1"))
+	assert.FileExists(t, filepath.Join(outside, "precious"))
+
+	// a link posing as snapshot is refused
+	assert.Error(t, removeElement(root, filepath.Join(root, "daily.2"), true))
+	assert.FileExists(t, filepath.Join(outside, "precious"))
+
+	// paths outside of root and root itself are refused
+	assert.Error(t, removeElement(root, outside, true))
+	assert.Error(t, removeElement(root, filepath.Join(root, "daily.2", "precious"), false))
+	assert.Error(t, removeElement(root, root, true))
+	assert.DirExists(t, outside)
+	assert.FileExists(t, filepath.Join(outside, "precious"))
+
+	// a plain file element is removed
+	assert.NoError(t, removeElement(root, filepath.Join(root, "README"), false))
+	assert.NoFileExists(t, filepath.Join(root, "README"))
+	assert.DirExists(t, filepath.Join(root, "daily.0"))
+}
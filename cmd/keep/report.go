@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/jojomi/keep"
+)
+
+// reportKinds are the kinds of reports supported by --report.
+var reportKinds = []string{"ascii", "html", "svg"}
+
+// reportSpec is a report requested with --report as kind[:path].
+type reportSpec struct {
+	Kind string
+	// Path is the file to write the report to, empty for the info output.
+	Path string
+}
+
+// parseReportSpecs parses the values of --report, so unknown kinds are rejected before anything is evaluated.
+func parseReportSpecs(specs []string) ([]reportSpec, error) {
+	reports := make([]reportSpec, 0, len(specs))
+	for _, spec := range specs {
+		kind, path, _ := strings.Cut(spec, ":")
+		kind = strings.ToLower(kind)
+		if !slices.Contains(reportKinds, kind) {
+			return nil, fmt.Errorf("unknown report kind %q in %q, try %v", kind, spec, reportKinds)
+		}
+		reports = append(reports, reportSpec{
+			Kind: kind,
+			Path: path,
+		})
+	}
+	return reports, nil
+}
+
+// writeReports renders the timeline of jh for every report. Without a path the report is written to info, which also
+// gets a note for every file written.
+func writeReports[T prunable](jh *keep.Jailhouse[T], reports []reportSpec, info io.Writer) error {
+	for _, report := range reports {
+		path := report.Path
+
+		var render func(io.Writer) error
+		switch report.Kind {
+		case "ascii":
+			render = func(w io.Writer) error {
+				_, err := io.WriteString(w, jh.RenderASCII(80))
+				return err
+			}
+		case "html":
+			render = jh.RenderHTML
+		case "svg":
+			render = jh.RenderSVG
+		default:
+			return withExitCode(exitUsage, fmt.Errorf("unknown report kind %q, try %v", report.Kind, reportKinds))
+		}
+
+		if path == "" {
+			fmt.Fprintln(info)
+			if err := render(info); err != nil {
+				return err
+			}
+			continue
+		}
+
+		f, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		err = render(f)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(info, "\nReport written to %s\n", path)
+	}
+	return nil
+}
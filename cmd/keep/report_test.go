@@ -0,0 +1,32 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jojomi/keep"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteReports(t *testing.T) {
+	jh, _ := outputJailhouse(t, keep.AnchorYoungest, false)
+	path := filepath.Join(t.TempDir(), "report.svg")
+
+	var info bytes.Buffer
+	reports, err := parseReportSpecs([]string{"ASCII", "svg:" + path})
+	assert.NoError(t, err)
+	assert.Equal(t, []reportSpec{{Kind: "ascii"}, {Kind: "svg", Path: path}}, reports)
+	assert.NoError(t, writeReports(jh, reports, &info))
+	assert.Contains(t, info.String(), jh.RenderASCII(80))
+	assert.Contains(t, info.String(), "Report written to "+path)
+
+	data, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), "<svg")
+
+	_, err = parseReportSpecs([]string{"pdf:out.pdf"})
+	assert.ErrorContains(t, err, `unknown report kind "pdf"`)
+	assert.Equal(t, exitUsage, exitCode(writeReports(jh, []reportSpec{{Kind: "pdf"}}, &info)))
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+func getRestoreCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "restore [path]",
+		Short: "move quarantined elements back to their directory",
+		Args:  cobra.MaximumNArgs(1),
+		RunE:  runRestore,
+	}
+
+	flags := cmd.Flags()
+	flags.String("quarantine-dir", "", "quarantine directory used with --action quarantine")
+	flags.StringSlice("include", nil, "only restore elements whose name or relative path matches one of these glob patterns")
+	_ = cmd.MarkFlagRequired("quarantine-dir")
+
+	return cmd
+}
+
+func runRestore(cmd *cobra.Command, args []string) error {
+	cmd.SilenceUsage = true
+	err := applyEnvironment(cmd.Flags())
+	if err != nil {
+		return withExitCode(exitUsage, err)
+	}
+	env, err := parseEnvRestore(cmd, args)
+	if err != nil {
+		return withExitCode(exitUsage, err)
+	}
+
+	if env.DryRun {
+		files, err := scanFiles(env.QuarantineDir, fileFilter{Recursive: true, Hidden: true, Include: env.Include})
+		if err != nil {
+			return withExitCode(exitScan, err)
+		}
+		for _, file := range files {
+			fmt.Printf("[DRY-RUN] Would be restoring %s...\n", file.RelPath)
+		}
+		return nil
+	}
+
+	restored, err := restoreQuarantine(env.QuarantineDir, env.Target, env.Include)
+	for _, path := range restored {
+		fmt.Printf("restored %s\n", path)
+	}
+	if err != nil {
+		return withExitCode(exitPartialFailure, err)
+	}
+	fmt.Printf("restored %d elements\n", len(restored))
+	return nil
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jojomi/keep"
+)
+
+// reviewElements opens the elements of a target in the editor of the user, who can switch lines between keep and
+// delete. It returns the elements to remove afterwards.
+func reviewElements(root string, elements []*keep.JailhouseTimeResource[fileElement], reasons *reasonRecorder[fileElement]) ([]*keep.JailhouseTimeResource[fileElement], error) {
+	if !stdinIsTerminal() {
+		return nil, withExitCode(exitAborted, errors.New("cannot review without a terminal on stdin"))
+	}
+
+	file, err := os.CreateTemp("", "keep-review-*.txt")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(file.Name())
+
+	err = writeReview(file, root, elements, reasons)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	err = runEditor(file.Name())
+	if err != nil {
+		return nil, withExitCode(exitAborted, err)
+	}
+
+	file, err = os.Open(file.Name())
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return parseReview(file, elements)
+}
+
+// writeReview writes one line per element to w: "keep" or "delete", its number, and its path, followed by its time
+// and tags (or with --reasons why it is kept or free) as comment.
+func writeReview(w io.Writer, root string, elements []*keep.JailhouseTimeResource[fileElement], reasons *reasonRecorder[fileElement]) error {
+	fmt.Fprintf(w, "# Review of %s, youngest first.\n", root)
+	fmt.Fprintln(w, "# Switch lines between keep (k) and delete (d). Removing a line keeps the element,")
+	fmt.Fprintln(w, "# removing all lines keeps everything. Only the first two words of a line are read.")
+	fmt.Fprintln(w)
+	for i, element := range elements {
+		action := "keep  "
+		if element.IsFree() {
+			action = "delete"
+		}
+		details := element.GetTime().Format(time.RFC3339)
+		if reason := reasons.Reason(element); reason != "" {
+			details += ", " + reason
+		} else if !element.IsFree() {
+			details = element.String()
+		}
+		_, err := fmt.Fprintf(w, "%s %d %s # %s\n", action, i+1, displayPath(element.TimeResource), details)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseReview reads a review written by writeReview and returns the elements marked for deletion.
+func parseReview(r io.Reader, elements []*keep.JailhouseTimeResource[fileElement]) ([]*keep.JailhouseTimeResource[fileElement], error) {
+	marked := make(map[int]bool)
+	scanner := bufio.NewScanner(r)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("review line %d: expected action and number, got %q", lineNumber, line)
+		}
+		index, err := strconv.Atoi(fields[1])
+		if err != nil || index < 1 || index > len(elements) {
+			return nil, fmt.Errorf("review line %d: unknown element %q", lineNumber, fields[1])
+		}
+		if _, ok := marked[index-1]; ok {
+			return nil, fmt.Errorf("review line %d: element %d listed twice", lineNumber, index)
+		}
+		switch fields[0] {
+		case "delete", "d":
+			marked[index-1] = true
+		case "keep", "k":
+			marked[index-1] = false
+		default:
+			return nil, fmt.Errorf("review line %d: unknown action %q, try [keep, delete]", lineNumber, fields[0])
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	result := make([]*keep.JailhouseTimeResource[fileElement], 0)
+	for i, element := range elements {
+		if marked[i] {
+			result = append(result, element)
+		}
+	}
+	return result, nil
+}
+
+// runEditor opens path in the editor given by $VISUAL or $EDITOR, vi if neither is set.
+func runEditor(path string) error {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+	args, err := splitCommandLine(editor)
+	if err != nil {
+		return fmt.Errorf("parsing editor %q: %w", editor, err)
+	}
+	if len(args) == 0 {
+		return fmt.Errorf("empty editor command")
+	}
+
+	cmd := exec.Command(args[0], append(args[1:], path)...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	err = cmd.Run()
+	if err != nil {
+		return fmt.Errorf("running editor %s: %w", editor, err)
+	}
+	return nil
+}
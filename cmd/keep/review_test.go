@@ -0,0 +1,61 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jojomi/keep"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReview(t *testing.T) {
+	jh := keep.NewDefaultJailhouse[fileElement]()
+	now := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < 4; i++ {
+		jh.AddElements(fileElement{Filename: "/backups/pg-" + string(rune('a'+i)), Time: now.AddDate(0, 0, -i)})
+	}
+	jh.ApplyRequirementsForDate(*keep.NewRequirementsFromString("2 last"), now)
+	elements := jh.Elements()
+
+	var b bytes.Buffer
+	assert.NoError(t, writeReview(&b, "/backups", elements, nil))
+	assert.Contains(t, b.String(), "keep   1 /backups/pg-a # ")
+	assert.Contains(t, b.String(), "delete 4 /backups/pg-d # 2024-03-07T12:00:00Z\n")
+
+	// unchanged review removes the free elements
+	removals, err := parseReview(strings.NewReader(b.String()), elements)
+	assert.NoError(t, err)
+	assert.Equal(t, jh.FreeElements(), removals)
+
+	tests := []struct {
+		name    string
+		review  string
+		want    []string
+		wantErr string
+	}{
+		{name: "switched", review: "# comment\nd 2 pg-b\nkeep 3 pg-c\n\ndelete 4\nk 1\n", want: []string{"/backups/pg-b", "/backups/pg-d"}},
+		{name: "removed lines are kept", review: "delete 3\n", want: []string{"/backups/pg-c"}},
+		{name: "empty keeps everything", review: "", want: []string{}},
+		{name: "unknown action", review: "drop 3\n", wantErr: "unknown action"},
+		{name: "unknown element", review: "delete 5\n", wantErr: "unknown element"},
+		{name: "twice", review: "delete 3\nkeep 3\n", wantErr: "listed twice"},
+		{name: "missing number", review: "delete\n", wantErr: "expected action and number"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			removals, err := parseReview(strings.NewReader(tt.review), elements)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			paths := make([]string, len(removals))
+			for i, element := range removals {
+				paths[i] = element.TimeResource.Filename
+			}
+			assert.Equal(t, tt.want, paths)
+		})
+	}
+}
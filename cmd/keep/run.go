@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+func getRunCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "run [job...]",
+		Short: "run the pruning jobs of a config file, all of them if none are given",
+		RunE:  runRun,
+	}
+
+	flags := cmd.Flags()
+	flags.StringP("config", "c", defaultConfigFile, "config file declaring the jobs")
+
+	return cmd
+}
+
+func runRun(cmd *cobra.Command, args []string) error {
+	cmd.SilenceUsage = true
+	err := applyEnvironment(cmd.Flags())
+	if err != nil {
+		return withExitCode(exitUsage, err)
+	}
+	env, err := parseEnvRun(cmd, args)
+	if err != nil {
+		return withExitCode(exitUsage, err)
+	}
+
+	c, err := loadConfig(env.Config)
+	if err != nil {
+		return withExitCode(exitUsage, err)
+	}
+	jobs, err := c.SelectJobs(env.Jobs)
+	if err != nil {
+		return withExitCode(exitUsage, err)
+	}
+
+	// parse all jobs first, so a broken one does not stop the others halfway
+	envs := make([]EnvRoot, 0, len(jobs))
+	for _, job := range jobs {
+		jobEnv, err := parseEnvJob(c, job, cmd.Flags())
+		if err != nil {
+			return withExitCode(exitUsage, err)
+		}
+		envs = append(envs, jobEnv)
+	}
+
+	// a failing job does not stop the others, the exit code is the one of the first failure
+	var (
+		removed  int
+		firstErr error
+		failed   []string
+	)
+	for _, jobEnv := range envs {
+		jobRemoved, err := runEnvRoot(jobEnv)
+		removed += jobRemoved
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "job %s: %v\n", jobEnv.Job, err)
+			if firstErr == nil {
+				firstErr = err
+			}
+			failed = append(failed, jobEnv.Job)
+		}
+	}
+	if firstErr != nil {
+		return withExitCode(exitCode(firstErr), fmt.Errorf("%d of %d jobs failed: %s", len(failed), len(envs), strings.Join(failed, ", ")))
+	}
+	if removed == 0 && env.DetailedExitCodes {
+		return withExitCode(exitNothingRemoved, errNothingRemoved)
+	}
+	return nil
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// fileFilter selects the files of a target directory that are considered for pruning.
+type fileFilter struct {
+	Recursive  bool
+	Include    []string
+	Exclude    []string
+	Extensions []string
+	MinSize    int64
+	Hidden     bool
+}
+
+// scannedFile is a file found in a target directory.
+type scannedFile struct {
+	// Path is the path to the file, including the target directory.
+	Path string
+	// RelPath is the path relative to the target directory, always using slashes.
+	RelPath string
+}
+
+// scanFiles returns the regular files in root matching the filter, descending into subdirectories if recursive.
+func scanFiles(root string, filter fileFilter) ([]scannedFile, error) {
+	// WalkDir does not descend into a root that is a symbolic link, so walk its target instead
+	resolved, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return nil, err
+	}
+
+	files := make([]scannedFile, 0)
+	err = filepath.WalkDir(resolved, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path == resolved {
+			return nil
+		}
+		if !filter.Hidden && strings.HasPrefix(d.Name(), ".") {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			if !filter.Recursive {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		relPath, err := filepath.Rel(resolved, path)
+		if err != nil {
+			return err
+		}
+
+		ok, err := filter.matches(filepath.ToSlash(relPath), d)
+		if err != nil || !ok {
+			return err
+		}
+		files = append(files, scannedFile{
+			Path:    filepath.Join(root, relPath),
+			RelPath: filepath.ToSlash(relPath),
+		})
+		return nil
+	})
+	return files, err
+}
+
+func (x fileFilter) matches(relPath string, d fs.DirEntry) (bool, error) {
+	if len(x.Include) > 0 {
+		included, err := matchesAnyGlob(x.Include, relPath)
+		if err != nil || !included {
+			return false, err
+		}
+	}
+	excluded, err := matchesAnyGlob(x.Exclude, relPath)
+	if err != nil || excluded {
+		return false, err
+	}
+
+	if len(x.Extensions) > 0 {
+		found := false
+		for _, ext := range x.Extensions {
+			if strings.HasSuffix(d.Name(), "."+strings.TrimPrefix(ext, ".")) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false, nil
+		}
+	}
+
+	if x.MinSize > 0 {
+		info, err := d.Info()
+		if err != nil {
+			return false, err
+		}
+		if info.Size() < x.MinSize {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// scanDirs returns the immediate subdirectories of root matching the filter's hidden, include, and exclude rules.
+// Symbolic links are never returned, even if they point to directories.
+func scanDirs(root string, filter fileFilter) ([]scannedFile, error) {
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return nil, err
+	}
+
+	dirs := make([]scannedFile, 0)
+	for _, entry := range entries {
+		// entries are not resolved, so symbolic links are not directories here
+		if !entry.IsDir() {
+			continue
+		}
+		if !filter.Hidden && strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		if len(filter.Include) > 0 {
+			included, err := matchesAnyGlob(filter.Include, entry.Name())
+			if err != nil {
+				return nil, err
+			}
+			if !included {
+				continue
+			}
+		}
+		excluded, err := matchesAnyGlob(filter.Exclude, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		if excluded {
+			continue
+		}
+
+		dirs = append(dirs, scannedFile{
+			Path:    filepath.Join(root, entry.Name()),
+			RelPath: entry.Name(),
+		})
+	}
+	return dirs, nil
+}
+
+// matchesAnyGlob reports whether the file name or the relative path matches one of the glob patterns.
+func matchesAnyGlob(patterns []string, relPath string) (bool, error) {
+	name := relPath[strings.LastIndex(relPath, "/")+1:]
+	for _, pattern := range patterns {
+		for _, candidate := range []string{name, relPath} {
+			ok, err := filepath.Match(pattern, candidate)
+			if err != nil {
+				return false, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+			}
+			if ok {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// parseSize parses a size in bytes with an optional unit suffix K, M, G, or T (powers of 1024).
+func parseSize(size string) (int64, error) {
+	size = strings.ToUpper(strings.TrimSpace(size))
+	if size == "" {
+		return 0, nil
+	}
+	factor := int64(1)
+	for i, unit := range []string{"K", "M", "G", "T"} {
+		if strings.HasSuffix(strings.TrimSuffix(size, "B"), unit) {
+			factor = int64(1) << (10 * (i + 1))
+			size = strings.TrimSuffix(strings.TrimSuffix(size, "B"), unit)
+			break
+		}
+	}
+	value, err := strconv.ParseInt(strings.TrimSuffix(size, "B"), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid size %q: %w", size, err)
+	}
+	return value * factor, nil
+}
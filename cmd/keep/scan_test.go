@@ -0,0 +1,119 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScanFiles(t *testing.T) {
+	root := t.TempDir()
+	for path, size := range map[string]int{
+		"a.sql.gz":          10,
+		"b.sql.gz":          1000,
+		"notes.txt":         10,
+		".hidden.sql.gz":    10,
+		"sub/c.sql.gz":      10,
+		"sub/deep/d.sql.gz": 10,
+		".snap/e.sql.gz":    10,
+	} {
+		full := filepath.Join(root, filepath.FromSlash(path))
+		assert.NoError(t, os.MkdirAll(filepath.Dir(full), 0o755))
+		assert.NoError(t, os.WriteFile(full, make([]byte, size), 0o644))
+	}
+
+	tests := []struct {
+		name   string
+		filter fileFilter
+		want   []string
+	}{
+		{
+			name:   "default",
+			filter: fileFilter{},
+			want:   []string{"a.sql.gz", "b.sql.gz", "notes.txt"},
+		},
+		{
+			name:   "recursive",
+			filter: fileFilter{Recursive: true},
+			want:   []string{"a.sql.gz", "b.sql.gz", "notes.txt", "sub/c.sql.gz", "sub/deep/d.sql.gz"},
+		},
+		{
+			name:   "hidden",
+			filter: fileFilter{Recursive: true, Hidden: true},
+			want:   []string{".hidden.sql.gz", ".snap/e.sql.gz", "a.sql.gz", "b.sql.gz", "notes.txt", "sub/c.sql.gz", "sub/deep/d.sql.gz"},
+		},
+		{
+			name:   "include",
+			filter: fileFilter{Recursive: true, Include: []string{"*.sql.gz"}},
+			want:   []string{"a.sql.gz", "b.sql.gz", "sub/c.sql.gz", "sub/deep/d.sql.gz"},
+		},
+		{
+			name:   "include relative path",
+			filter: fileFilter{Recursive: true, Include: []string{"sub/*"}},
+			want:   []string{"sub/c.sql.gz"},
+		},
+		{
+			name:   "exclude",
+			filter: fileFilter{Recursive: true, Exclude: []string{"sub/*", "a.*"}},
+			want:   []string{"b.sql.gz", "notes.txt", "sub/deep/d.sql.gz"},
+		},
+		{
+			name:   "extension",
+			filter: fileFilter{Extensions: []string{".txt"}},
+			want:   []string{"notes.txt"},
+		},
+		{
+			name:   "min size",
+			filter: fileFilter{MinSize: 100},
+			want:   []string{"b.sql.gz"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files, err := scanFiles(root, tt.filter)
+			assert.NoError(t, err)
+
+			got := make([]string, len(files))
+			for i, file := range files {
+				got[i] = file.RelPath
+				assert.Equal(t, filepath.Join(root, filepath.FromSlash(file.RelPath)), file.Path)
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestScanFiles_SymlinkRoot(t *testing.T) {
+	target := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(target, "a.sql.gz"), nil, 0o644))
+	root := filepath.Join(t.TempDir(), "backups")
+	assert.NoError(t, os.Symlink(target, root))
+
+	files, err := scanFiles(root, fileFilter{})
+	assert.NoError(t, err)
+	assert.Equal(t, []scannedFile{{Path: filepath.Join(root, "a.sql.gz"), RelPath: "a.sql.gz"}}, files)
+
+	_, err = scanFiles(filepath.Join(t.TempDir(), "missing"), fileFilter{})
+	assert.Error(t, err)
+}
+
+func TestParseSize(t *testing.T) {
+	for input, want := range map[string]int64{
+		"":      0,
+		"512":   512,
+		"10K":   10 << 10,
+		"10kb":  10 << 10,
+		"3M":    3 << 20,
+		"1G":    1 << 30,
+		" 2T  ": 2 << 40,
+	} {
+		got, err := parseSize(input)
+		assert.NoError(t, err, input)
+		assert.Equal(t, want, got, input)
+	}
+
+	_, err := parseSize("ten")
+	assert.Error(t, err)
+}
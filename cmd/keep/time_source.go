@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/djherbis/times"
+	"github.com/jojomi/keep"
+)
+
+// timeSources maps the names accepted by --time-source to a function reading that time, returning false if the
+// time is not available.
+var timeSources = map[string]func(times.Timespec) (time.Time, bool){
+	"birth": func(t times.Timespec) (time.Time, bool) {
+		if !t.HasBirthTime() {
+			return time.Time{}, false
+		}
+		return t.BirthTime(), true
+	},
+	"mtime": func(t times.Timespec) (time.Time, bool) {
+		return t.ModTime(), true
+	},
+	"ctime": func(t times.Timespec) (time.Time, bool) {
+		if !t.HasChangeTime() {
+			return time.Time{}, false
+		}
+		return t.ChangeTime(), true
+	},
+	"atime": func(t times.Timespec) (time.Time, bool) {
+		return t.AccessTime(), true
+	},
+}
+
+// nameTimeSource is the time source reading the time from the file path using a keep.TimeExtractor.
+const nameTimeSource = "name"
+
+// parseTimeSources parses a comma-separated, ordered list of time sources like "birth,mtime".
+func parseTimeSources(spec string) ([]string, error) {
+	sources := make([]string, 0)
+	for _, source := range strings.Split(spec, ",") {
+		source = strings.ToLower(strings.TrimSpace(source))
+		if _, ok := timeSources[source]; !ok && source != nameTimeSource {
+			return nil, fmt.Errorf("unknown time source %q, try [name, birth, mtime, ctime, atime]", source)
+		}
+		sources = append(sources, source)
+	}
+	return sources, nil
+}
+
+// newTimeExtractor creates the keep.TimeExtractor for the name time source from a pattern of type layout,
+// strftime, or regexp, reading times in the named location.
+func newTimeExtractor(pattern, patternType, location string) (*keep.TimeExtractor, error) {
+	loc, err := time.LoadLocation(location)
+	if err != nil {
+		return nil, err
+	}
+	switch strings.ToLower(patternType) {
+	case "layout":
+		return keep.NewTimeExtractorFromLayout(pattern, loc)
+	case "strftime":
+		return keep.NewTimeExtractorFromStrftime(pattern, loc)
+	case "regexp":
+		return keep.NewTimeExtractorFromRegexp(pattern, loc)
+	default:
+		return nil, fmt.Errorf("unknown time pattern type %q, try [layout, strftime, regexp]", patternType)
+	}
+}
+
+// fileTime returns the time of the file from the first of the given sources that is available, and the name of that
+// source. extractor is used for the name source on the relative path and may be nil otherwise.
+func fileTime(file scannedFile, sources []string, extractor *keep.TimeExtractor) (time.Time, string, error) {
+	var (
+		t   times.Timespec
+		err error
+	)
+	for _, source := range sources {
+		if source == nameTimeSource {
+			if extractor == nil {
+				return time.Time{}, "", fmt.Errorf("time source %s requires --time-pattern", nameTimeSource)
+			}
+			value, err := extractor.Extract(file.RelPath)
+			if err == nil {
+				return value, source, nil
+			}
+			continue
+		}
+
+		if t == nil {
+			t, err = times.Stat(file.Path)
+			if err != nil {
+				return time.Time{}, "", err
+			}
+		}
+		value, ok := timeSources[source](t)
+		if ok && !value.IsZero() {
+			return value, source, nil
+		}
+	}
+	return time.Time{}, "", fmt.Errorf("no time available from %s", strings.Join(sources, ", "))
+}
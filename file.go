@@ -14,6 +14,11 @@ func (x File) GetTime() time.Time {
 	return x.Time
 }
 
+// TieBreakKey orders files with the same time by their filename.
+func (x File) TieBreakKey() string {
+	return x.Filename
+}
+
 func (x File) String() string {
 	return fmt.Sprintf("File %s with date %s", x.Filename, x.GetTime().Format("02.01.2006 15:04:05 Uhr"))
 }
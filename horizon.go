@@ -0,0 +1,158 @@
+package keep
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Horizon describes how far back a Requirements definition reaches and how many elements it holds in steady state,
+// assuming elements are added at a regular interval.
+type Horizon struct {
+	ReferenceDate time.Time `json:"referenceDate"`
+	// Elements is the number of elements held in steady state.
+	Elements int `json:"elements"`
+	// Oldest is the expected time of the oldest element kept, MinOldest and MaxOldest bound it if the interval jitters.
+	Oldest    time.Time      `json:"oldest"`
+	MinOldest time.Time      `json:"minOldest"`
+	MaxOldest time.Time      `json:"maxOldest"`
+	Levels    []LevelHorizon `json:"levels"`
+}
+
+// LevelHorizon is the part of a Horizon covered by a single level.
+type LevelHorizon struct {
+	Level    TimeRange `json:"level"`
+	Elements int       `json:"elements"`
+	// Youngest and Oldest are the expected times of the youngest and oldest element of this level.
+	Youngest time.Time `json:"youngest"`
+	Oldest   time.Time `json:"oldest"`
+}
+
+// Reach returns how far back from the reference date the oldest element is expected.
+func (x Horizon) Reach() time.Duration {
+	return x.ReferenceDate.Sub(x.Oldest)
+}
+
+func (x Horizon) String() string {
+	lines := []string{
+		fmt.Sprintf("%d elements in steady state", x.Elements),
+		fmt.Sprintf("oldest element from %s (%s)", x.Oldest.Format(time.RFC3339), formatAge(x.ReferenceDate.Sub(x.Oldest))),
+	}
+	if !x.MinOldest.Equal(x.MaxOldest) {
+		lines = append(lines, fmt.Sprintf("at least %s, at most %s", formatAge(x.ReferenceDate.Sub(x.MinOldest)), formatAge(x.ReferenceDate.Sub(x.MaxOldest))))
+	}
+	for _, level := range x.Levels {
+		lines = append(lines, fmt.Sprintf("%s: %d elements from %s to %s", level.Level, level.Elements, formatAge(x.ReferenceDate.Sub(level.Youngest)), formatAge(x.ReferenceDate.Sub(level.Oldest))))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Horizon calculates the Horizon of the given Requirements for elements added every interval, the last one at
+// referenceDate. If jitter is given, the actual distance between elements is assumed to vary by up to jitter in both
+// directions, which is reflected in MinOldest and MaxOldest. Anchored at the oldest element, the level grid starts at
+// the epoch (see SetEpoch), or at referenceDate if there is none.
+func (x *Jailhouse[T]) Horizon(reqs Requirements, interval, jitter time.Duration, referenceDate time.Time) Horizon {
+	horizon := Horizon{
+		ReferenceDate: referenceDate,
+	}
+	horizon.Oldest, horizon.Levels = x.walkHorizon(reqs, interval, 0, referenceDate)
+	for _, level := range horizon.Levels {
+		horizon.Elements += level.Elements
+	}
+
+	horizon.MinOldest, horizon.MaxOldest = horizon.Oldest, horizon.Oldest
+	if jitter > 0 {
+		// the element closest to a level step is at most half of the largest distance between elements away
+		slip := (interval + jitter) / 2
+		horizon.MinOldest, _ = x.walkHorizon(reqs, interval-jitter, slip, referenceDate)
+		horizon.MaxOldest, _ = x.walkHorizon(reqs, interval+jitter, -slip, referenceDate)
+	}
+	return horizon
+}
+
+// walkHorizon follows the levels back in time using elements exactly gap apart, moving each level step by slip.
+func (x *Jailhouse[T]) walkHorizon(reqs Requirements, gap, slip time.Duration, referenceDate time.Time) (time.Time, []LevelHorizon) {
+	var (
+		levels  = make([]LevelHorizon, 0)
+		current = referenceDate
+		oldest  = referenceDate
+	)
+	if gap <= 0 {
+		gap = time.Nanosecond
+	}
+
+	epoch := x.epoch
+	if epoch.IsZero() {
+		epoch = referenceDate
+	}
+
+	for _, level := range x.GetLevels() {
+		required := int(reqs.Get(level))
+		if required == 0 {
+			continue
+		}
+
+		levelHorizon := LevelHorizon{
+			Level:    level,
+			Elements: required,
+			Youngest: current,
+		}
+		if x.anchor == AnchorOldest && level != LAST {
+			// every cell is represented by its oldest element, the first one of the cell in the timeline
+			var cellStart time.Time
+			for i := 0; i < required; i++ {
+				if i > 0 {
+					// the next element, which lies in an older cell even if slip moved the representative
+					current = current.Add(-gap)
+					if !current.Before(cellStart) {
+						current = cellStart.Add(-time.Nanosecond)
+					}
+				}
+				cellStart = x.cellStart(level, epoch, current)
+				first := referenceDate.Add(-referenceDate.Sub(cellStart) / gap * gap).Add(slip)
+				if first.Before(cellStart) {
+					first = cellStart
+				}
+				if first.Before(current) {
+					current = first
+				}
+				if i == 0 {
+					levelHorizon.Youngest = current
+				}
+			}
+			levelHorizon.Oldest = current
+			oldest = current
+			levels = append(levels, levelHorizon)
+			current = current.Add(-gap)
+			continue
+		}
+		for i := 1; i < required; i++ {
+			next := x.addLevelStep(level, current).Add(slip)
+			// elements are never closer than gap
+			if current.Sub(next) < gap {
+				next = current.Add(-gap)
+			}
+			current = next
+		}
+		levelHorizon.Oldest = current
+		oldest = current
+		levels = append(levels, levelHorizon)
+
+		// the next level starts at the next older element
+		current = current.Add(-gap)
+	}
+	return oldest, levels
+}
+
+// formatAge prints a duration in days or years, which is more readable than hours for long durations.
+func formatAge(d time.Duration) string {
+	const day = 24 * time.Hour
+	switch {
+	case d < day:
+		return d.Round(time.Second).String()
+	case d < 365*day:
+		return fmt.Sprintf("%.1f days", float64(d)/float64(day))
+	default:
+		return fmt.Sprintf("%.1f years", float64(d)/float64(365.2425*float64(day)))
+	}
+}
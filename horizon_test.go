@@ -0,0 +1,80 @@
+package keep
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJailhouse_Horizon(t *testing.T) {
+	referenceDate := time.Date(2024, time.January, 20, 12, 0, 0, 0, time.UTC)
+	reqs := NewRequirementsFromString("10 last, 14 days, 12 weeks, 12 months, 12 years")
+
+	h := NewDefaultJailhouse[File]().Horizon(*reqs, time.Hour, 0, referenceDate)
+	assert.Equal(t, 60, h.Elements)
+	assert.Len(t, h.Levels, 5)
+	assert.Equal(t, h.Oldest, h.MinOldest)
+	assert.Equal(t, h.Oldest, h.MaxOldest)
+
+	// 10 hours, 13 days, 11 weeks, 11 months and 11 years back
+	want := referenceDate.Add(-9*time.Hour).Add(-time.Hour).AddDate(0, 0, -13).Add(-time.Hour).AddDate(0, 0, -77).Add(-time.Hour).AddDate(0, -11, 0).Add(-time.Hour).AddDate(-11, 0, 0)
+	assert.Equal(t, want, h.Oldest)
+	assert.InDelta(t, 12, h.Reach().Hours()/24/365, 1)
+
+	assert.Equal(t, LAST, h.Levels[0].Level)
+	assert.Equal(t, referenceDate, h.Levels[0].Youngest)
+	assert.Equal(t, referenceDate.Add(-9*time.Hour), h.Levels[0].Oldest)
+}
+
+func TestJailhouse_Horizon_Jitter(t *testing.T) {
+	referenceDate := time.Date(2024, time.January, 20, 12, 0, 0, 0, time.UTC)
+	reqs := NewRequirementsFromString("3 last, 7 days")
+
+	h := NewDefaultJailhouse[File]().Horizon(*reqs, time.Hour, 30*time.Minute, referenceDate)
+	assert.True(t, h.MinOldest.After(h.Oldest))
+	assert.True(t, h.MaxOldest.Before(h.Oldest))
+
+	// anchored at the oldest element, the oldest element stays in the oldest cell, at most one interval after its start
+	epoch := time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC)
+	h = NewDefaultJailhouse[File]().SetAnchor(AnchorOldest).SetEpoch(epoch).Horizon(*reqs, time.Hour, 30*time.Minute, referenceDate)
+	oldestCell := time.Date(2024, time.January, 14, 0, 0, 0, 0, time.UTC)
+	assert.Equal(t, oldestCell, h.Oldest)
+	assert.Equal(t, oldestCell, h.MaxOldest)
+	assert.True(t, h.MinOldest.After(h.Oldest))
+	assert.True(t, h.MinOldest.Before(oldestCell.Add(90*time.Minute)))
+}
+
+func TestJailhouse_Horizon_IntervalLargerThanStep(t *testing.T) {
+	referenceDate := time.Date(2024, time.January, 20, 12, 0, 0, 0, time.UTC)
+	reqs := NewRequirementsFromString("24 hours")
+
+	// daily elements can't be closer than a day, even if hourly ones are requested
+	h := NewDefaultJailhouse[File]().Horizon(*reqs, 24*time.Hour, 0, referenceDate)
+	assert.Equal(t, referenceDate.AddDate(0, 0, -23), h.Oldest)
+}
+
+func TestJailhouse_Horizon_MatchesApply(t *testing.T) {
+	referenceDate := time.Date(2024, time.January, 20, 12, 0, 0, 0, time.UTC)
+	epoch := time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC)
+	reqs := NewRequirementsFromString("3 last, 7 days, 4 weeks, 3 months")
+
+	// hourly elements since long before the horizon, as in steady state
+	elements := make([]TestTimeResource, 0)
+	for ti := referenceDate; ti.After(referenceDate.AddDate(-1, 0, 0)); ti = ti.Add(-time.Hour) {
+		elements = append(elements, TestTimeResource{t: ti})
+	}
+
+	for _, anchor := range []Anchor{AnchorYoungest, AnchorOldest} {
+		t.Run(anchor.String(), func(t *testing.T) {
+			x := NewDefaultJailhouse[TestTimeResource]().SetAnchor(anchor).SetEpoch(epoch)
+			x.AddElements(elements...)
+			x.ApplyRequirementsForDate(*reqs, referenceDate)
+			kept := x.KeptElements()
+
+			h := x.Horizon(*reqs, time.Hour, 0, referenceDate)
+			assert.Equal(t, len(kept), h.Elements)
+			assert.Equal(t, kept[len(kept)-1].GetTime(), h.Oldest)
+		})
+	}
+}
@@ -1,7 +1,9 @@
 package keep
 
 import (
+	"context"
 	"math"
+	"strings"
 	"time"
 
 	"github.com/juju/errors"
@@ -11,6 +13,17 @@ import (
 type Jailhouse[T TimeResource] struct {
 	elements []*JailhouseTimeResource[T]
 	levels   []TimeRange
+	anchor   Anchor
+	epoch    time.Time
+
+	// requirements and reference date last applied
+	requirements  Requirements
+	referenceDate time.Time
+
+	// observers, see OnTag, OnSkip, and OnFree
+	onTag  func(element *JailhouseTimeResource[T], tag TimeRangeTag)
+	onSkip func(element, neighbour *JailhouseTimeResource[T], level TimeRange)
+	onFree func(element *JailhouseTimeResource[T])
 }
 
 func NewDefaultJailhouse[T TimeResource]() *Jailhouse[T] {
@@ -27,6 +40,42 @@ func (x *Jailhouse[T]) GetLevels() []TimeRange {
 	return x.levels
 }
 
+func (x *Jailhouse[T]) GetAnchor() Anchor {
+	return x.anchor
+}
+
+// SetAnchor defines from which end of the timeline the levels are laid out, see Anchor.
+func (x *Jailhouse[T]) SetAnchor(anchor Anchor) *Jailhouse[T] {
+	x.anchor = anchor
+	return x
+}
+
+// SetEpoch fixes the start of the level grid for AnchorOldest. If unset, the oldest element is used,
+// which shifts the grid whenever the oldest element is removed.
+func (x *Jailhouse[T]) SetEpoch(epoch time.Time) *Jailhouse[T] {
+	x.epoch = epoch
+	return x
+}
+
+// OnTag registers a function called whenever an element is tagged for a level during evaluation.
+func (x *Jailhouse[T]) OnTag(fn func(element *JailhouseTimeResource[T], tag TimeRangeTag)) *Jailhouse[T] {
+	x.onTag = fn
+	return x
+}
+
+// OnSkip registers a function called whenever an element is passed over for a level in favour of a neighbour
+// that represents the same time better.
+func (x *Jailhouse[T]) OnSkip(fn func(element, neighbour *JailhouseTimeResource[T], level TimeRange)) *Jailhouse[T] {
+	x.onSkip = fn
+	return x
+}
+
+// OnFree registers a function called for every element left free at the end of an evaluation.
+func (x *Jailhouse[T]) OnFree(fn func(element *JailhouseTimeResource[T])) *Jailhouse[T] {
+	x.onFree = fn
+	return x
+}
+
 func (x *Jailhouse[T]) AddElements(elems ...T) *Jailhouse[T] {
 	// add
 	for _, e := range elems {
@@ -44,11 +93,70 @@ func (x *Jailhouse[T]) ApplyRequirements(reqs Requirements) *Jailhouse[T] {
 }
 
 func (x *Jailhouse[T]) ApplyRequirementsForDate(reqs Requirements, referenceDate time.Time) *Jailhouse[T] {
+	// a background context is never cancelled, so there is no error to report
+	_ = x.apply(context.Background(), reqs, referenceDate)
+	return x
+}
+
+// ApplyRequirementsForDateContext validates the requirements using ValidateRequirements and applies them like
+// ApplyRequirementsForDate, aborting when ctx is done. On error all elements are left without tags, so the result
+// must be discarded.
+func (x *Jailhouse[T]) ApplyRequirementsForDateContext(ctx context.Context, reqs Requirements, referenceDate time.Time) error {
+	err := x.ValidateRequirements(reqs)
+	if err != nil {
+		return err
+	}
+	return x.apply(ctx, reqs, referenceDate)
+}
+
+// ValidateRequirements returns an error if the requirements are invalid (see Requirements.Validate) or request
+// levels this Jailhouse does not support.
+func (x *Jailhouse[T]) ValidateRequirements(reqs Requirements) error {
+	err := reqs.Validate()
+	if err != nil {
+		return err
+	}
+	for _, level := range TimeRangeNames() {
+		timeRange := MustParseTimeRange(level)
+		if reqs.Get(timeRange) > 0 && !slices.Contains(x.GetLevels(), timeRange) {
+			return errors.NotSupportedf("level %s", timeRange)
+		}
+	}
+	return nil
+}
+
+func (x *Jailhouse[T]) apply(ctx context.Context, reqs Requirements, referenceDate time.Time) error {
+	x.requirements = reqs.DeepCopy()
+	x.referenceDate = referenceDate
+
 	// clear previous results
 	for _, item := range x.elements {
 		item.ClearTags()
 	}
 
+	// the loops only check ctx every few iterations, so make sure a context done before is never missed
+	err := ctx.Err()
+	if err != nil {
+		return err
+	}
+	if x.anchor == AnchorOldest {
+		err = x.applyOldestAnchored(ctx, reqs, referenceDate)
+	} else {
+		err = x.applyYoungestAnchored(ctx, reqs, referenceDate)
+	}
+	if err != nil {
+		// partial results must not be mistaken for complete ones
+		for _, item := range x.elements {
+			item.ClearTags()
+		}
+		return err
+	}
+
+	x.notifyFree()
+	return nil
+}
+
+func (x *Jailhouse[T]) applyYoungestAnchored(ctx context.Context, reqs Requirements, referenceDate time.Time) error {
 	// loop and keep or pass
 	var (
 		currentTime       = referenceDate
@@ -61,6 +169,7 @@ func (x *Jailhouse[T]) ApplyRequirementsForDate(reqs Requirements, referenceDate
 		nextItem          *JailhouseTimeResource[T]
 		elementCount      = len(x.elements)
 		levelStart        int
+		iterations        int
 	)
 
 	for _, level := range x.GetLevels() {
@@ -75,6 +184,9 @@ func (x *Jailhouse[T]) ApplyRequirementsForDate(reqs Requirements, referenceDate
 
 		levelStart = startElementIndex
 		for i := levelStart; i < elementCount; i++ {
+			if err := x.checkContext(ctx, &iterations); err != nil {
+				return err
+			}
 			item = x.elements[i]
 
 			// ignore the future
@@ -92,6 +204,7 @@ func (x *Jailhouse[T]) ApplyRequirementsForDate(reqs Requirements, referenceDate
 					// select either this one or the next, depending on which is closer to the "current time" we aim for
 					if math.Abs(float64(nextItem.GetTime().Sub(currentTime))) < math.Abs(float64(currentTime.Sub(item.GetTime()))) {
 						// this one is not in the output -> can be dropped
+						x.skip(item, nextItem, level)
 						continue
 					}
 				}
@@ -101,7 +214,7 @@ func (x *Jailhouse[T]) ApplyRequirementsForDate(reqs Requirements, referenceDate
 			nextTime, extendedTime, lastOfLevel, reqs = x.nextTickForLevel(item.GetTime(), reqs, level)
 
 			// mark
-			item.AddTag(TimeRangeTagFrom(level, uint16(levelElementIndex+1)))
+			x.tag(item, TimeRangeTagFrom(level, uint16(levelElementIndex+1)))
 			levelElementIndex++
 			startElementIndex = i + 1
 
@@ -114,7 +227,152 @@ func (x *Jailhouse[T]) ApplyRequirementsForDate(reqs Requirements, referenceDate
 	}
 
 	// os.Exit(1)
-	return x
+	return nil
+}
+
+// checkContext counts an iteration and returns the error of ctx once it is done, checking only every few iterations
+// since this is called for every element.
+func (x *Jailhouse[T]) checkContext(ctx context.Context, iterations *int) error {
+	*iterations++
+	if *iterations%1024 != 0 {
+		return nil
+	}
+	return ctx.Err()
+}
+
+func (x *Jailhouse[T]) tag(element *JailhouseTimeResource[T], tag TimeRangeTag) {
+	element.AddTag(tag)
+	if x.onTag != nil {
+		x.onTag(element, tag)
+	}
+}
+
+func (x *Jailhouse[T]) skip(element, neighbour *JailhouseTimeResource[T], level TimeRange) {
+	if x.onSkip != nil {
+		x.onSkip(element, neighbour, level)
+	}
+}
+
+func (x *Jailhouse[T]) notifyFree() {
+	if x.onFree == nil {
+		return
+	}
+	for _, element := range x.FreeElements() {
+		x.onFree(element)
+	}
+}
+
+func (x *Jailhouse[T]) applyOldestAnchored(ctx context.Context, reqs Requirements, referenceDate time.Time) error {
+	if len(x.elements) == 0 {
+		return nil
+	}
+
+	epoch := x.epoch
+	if epoch.IsZero() {
+		epoch = x.elements[len(x.elements)-1].GetTime()
+	}
+
+	var (
+		startElementIndex = 0
+		elementCount      = len(x.elements)
+		item              *JailhouseTimeResource[T]
+		iterations        int
+	)
+
+	for _, level := range x.GetLevels() {
+		required := int(reqs.Get(level))
+		if required == 0 {
+			continue
+		}
+
+		// collect the representatives, youngest cell first: elements are sorted youngest first, so the
+		// last element seen for a cell is the oldest one in it
+		var (
+			representatives []int
+			cellStart       time.Time
+		)
+		for i := startElementIndex; i < elementCount; i++ {
+			if err := x.checkContext(ctx, &iterations); err != nil {
+				return err
+			}
+			item = x.elements[i]
+
+			// ignore the future
+			if item.GetTime().After(referenceDate) {
+				continue
+			}
+
+			// for LAST we keep any element
+			if level == LAST {
+				representatives = append(representatives, i)
+				if len(representatives) == required {
+					break
+				}
+				continue
+			}
+
+			start := x.cellStart(level, epoch, item.GetTime())
+			if len(representatives) > 0 && start.Equal(cellStart) {
+				x.skip(x.elements[representatives[len(representatives)-1]], item, level)
+				representatives[len(representatives)-1] = i
+				continue
+			}
+			if len(representatives) == required {
+				break
+			}
+			representatives = append(representatives, i)
+			cellStart = start
+		}
+
+		for levelElementIndex, i := range representatives {
+			x.tag(x.elements[i], TimeRangeTagFrom(level, uint16(levelElementIndex+1)))
+			startElementIndex = i + 1
+		}
+	}
+	return nil
+}
+
+// cellStart returns the start of the grid cell of the given level that contains t, the grid starting at epoch.
+func (x *Jailhouse[T]) cellStart(level TimeRange, epoch, t time.Time) time.Time {
+	// estimate the number of steps, then correct for calendar irregularities
+	steps := int(t.Sub(epoch).Hours() / x.approxLevelStep(level).Hours())
+	for x.addLevelSteps(level, epoch, steps).After(t) {
+		steps--
+	}
+	for !x.addLevelSteps(level, epoch, steps+1).After(t) {
+		steps++
+	}
+	return x.addLevelSteps(level, epoch, steps)
+}
+
+func (x *Jailhouse[T]) approxLevelStep(level TimeRange) time.Duration {
+	const day = 24 * time.Hour
+	switch level {
+	case SECOND:
+		return time.Second
+	case MINUTE:
+		return time.Minute
+	case HOUR:
+		return time.Hour
+	case DAY:
+		return day
+	case WEEK:
+		return 7 * day
+	case MONTH:
+		return 30 * day
+	case QUARTER:
+		return 91 * day
+	case YEAR:
+		return 365 * day
+	case DECADE:
+		return 3652 * day
+	case CENTURY, MILLENIUM:
+		// durations are limited to about 290 years, the correction in cellStart takes care of the rest
+		return 36524 * day
+	default:
+		err := errors.Errorf("could not find level %s", level)
+		panic(err)
+	}
 }
 
 func (x *Jailhouse[T]) FilteredElements(filter func(*JailhouseTimeResource[T]) bool) []*JailhouseTimeResource[T] {
@@ -168,34 +426,36 @@ func (x *Jailhouse[T]) nextTickForLevel(current time.Time, requirements Requirem
 }
 
 func (x *Jailhouse[T]) addLevelStep(level TimeRange, current time.Time) time.Time {
+	return x.addLevelSteps(level, current, -1)
+}
+
+// addLevelSteps moves current by the given number of level steps, negative values moving into the past.
+func (x *Jailhouse[T]) addLevelSteps(level TimeRange, current time.Time, steps int) time.Time {
 	switch level {
 	case LAST:
 		return current
 	case SECOND:
-		d, _ := time.ParseDuration("-1s")
-		return current.Add(d)
+		return current.Add(time.Duration(steps) * time.Second)
 	case MINUTE:
-		d, _ := time.ParseDuration("-1m")
-		return current.Add(d)
+		return current.Add(time.Duration(steps) * time.Minute)
 	case HOUR:
-		d, _ := time.ParseDuration("-1h")
-		return current.Add(d)
+		return current.Add(time.Duration(steps) * time.Hour)
 	case DAY:
-		return current.AddDate(0, 0, -1)
+		return current.AddDate(0, 0, steps)
 	case WEEK:
-		return current.AddDate(0, 0, -7)
+		return current.AddDate(0, 0, 7*steps)
 	case MONTH:
-		return current.AddDate(0, -1, 0)
+		return current.AddDate(0, steps, 0)
 	case QUARTER:
-		return current.AddDate(0, -3, 0)
+		return current.AddDate(0, 3*steps, 0)
 	case YEAR:
-		return current.AddDate(-1, 0, 0)
+		return current.AddDate(steps, 0, 0)
 	case DECADE:
-		return current.AddDate(-10, 0, 0)
+		return current.AddDate(10*steps, 0, 0)
 	case CENTURY:
-		return current.AddDate(-100, 0, 0)
+		return current.AddDate(100*steps, 0, 0)
 	case MILLENIUM:
-		return current.AddDate(-1000, 0, 0)
+		return current.AddDate(1000*steps, 0, 0)
 	default:
 		err := errors.Errorf("could not find level %s", level)
 		panic(err)
@@ -203,9 +463,9 @@ func (x *Jailhouse[T]) addLevelStep(level TimeRange, current time.Time) time.Tim
 }
 
 func (x *Jailhouse[T]) sortResources(input []*JailhouseTimeResource[T]) {
-	slices.SortFunc(input, func(a, b *JailhouseTimeResource[T]) int {
+	slices.SortStableFunc(input, func(a, b *JailhouseTimeResource[T]) int {
 		if a.GetTime().Equal(b.GetTime()) {
-			return 0
+			return x.compareTieBreak(a.TimeResource, b.TimeResource)
 		}
 		if a.GetTime().After(b.GetTime()) {
 			return -1
@@ -213,3 +473,15 @@ func (x *Jailhouse[T]) sortResources(input []*JailhouseTimeResource[T]) {
 		return 1
 	})
 }
+
+func (x *Jailhouse[T]) compareTieBreak(a, b T) int {
+	aTieBreaker, ok := any(a).(TieBreaker)
+	if !ok {
+		return 0
+	}
+	bTieBreaker, ok := any(b).(TieBreaker)
+	if !ok {
+		return 0
+	}
+	return strings.Compare(aTieBreaker.TieBreakKey(), bTieBreaker.TieBreakKey())
+}
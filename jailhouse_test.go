@@ -1,9 +1,15 @@
 package keep
 
 import (
+	"context"
+	stderrors "errors"
+	"math/rand"
 	"sort"
 	"testing"
 	"time"
+
+	"github.com/juju/errors"
+	"github.com/stretchr/testify/assert"
 )
 
 func TestJailhouse_KeptElements(t *testing.T) {
@@ -139,6 +145,443 @@ func TestJailhouse_KeptElements(t *testing.T) {
 	}
 }
 
+func TestJailhouse_KeptElements_AnchorOldest(t *testing.T) {
+	testDate := time.Date(2024, time.January, 20, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name         string
+		elements     []TestTimeResource
+		epoch        time.Time
+		requirements *Requirements
+		want         []*JailhouseTimeResource[TestTimeResource]
+	}{
+		{
+			name: "first of month",
+			elements: []TestTimeResource{
+				date("2024-01-19"),
+				date("2024-01-03"), // MONTH-1
+				date("2023-12-28"),
+				date("2023-12-02"), // MONTH-2
+				date("2023-11-15"),
+				date("2023-11-01"), // MONTH-3
+			},
+			epoch:        date("2000-01-01").t,
+			requirements: NewRequirements().Add(MONTH, 3),
+			want: []*JailhouseTimeResource[TestTimeResource]{
+				NewJailhouseTimeResource(date("2024-01-03")).AddTag(TimeRangeTagFrom(MONTH, 1)),
+				NewJailhouseTimeResource(date("2023-12-02")).AddTag(TimeRangeTagFrom(MONTH, 2)),
+				NewJailhouseTimeResource(date("2023-11-01")).AddTag(TimeRangeTagFrom(MONTH, 3)),
+			},
+		},
+		{
+			name: "oldest element as epoch",
+			elements: []TestTimeResource{
+				date("2024-01-19"), // LAST-1
+				date("2024-01-18"),
+				date("2024-01-17"), // WEEK-1
+				date("2024-01-12"),
+				date("2024-01-10"), // WEEK-2
+				date("2024-01-09"),
+				date("2024-01-03"), // WEEK-3
+			},
+			requirements: NewRequirements().Add(LAST, 1).Add(WEEK, 3),
+			want: []*JailhouseTimeResource[TestTimeResource]{
+				NewJailhouseTimeResource(date("2024-01-19")).AddTag(TimeRangeTagFrom(LAST, 1)),
+				NewJailhouseTimeResource(date("2024-01-17")).AddTag(TimeRangeTagFrom(WEEK, 1)),
+				NewJailhouseTimeResource(date("2024-01-10")).AddTag(TimeRangeTagFrom(WEEK, 2)),
+				NewJailhouseTimeResource(date("2024-01-03")).AddTag(TimeRangeTagFrom(WEEK, 3)),
+			},
+		},
+		{
+			name: "ignore future",
+			elements: []TestTimeResource{
+				date("2025-01-01"),
+				date("2023-02-22"),
+			},
+			requirements: NewRequirements().Add(DAY, 1),
+			want: []*JailhouseTimeResource[TestTimeResource]{
+				NewJailhouseTimeResource(date("2023-02-22")).AddTag(TimeRangeTagFrom(DAY, 1)),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			x := NewDefaultJailhouse[TestTimeResource]().SetAnchor(AnchorOldest).SetEpoch(tt.epoch)
+			x.AddElements(tt.elements...)
+			x.ApplyRequirementsForDate(*tt.requirements, testDate)
+			assertSameElements(t, tt.want, x.KeptElements())
+		})
+	}
+}
+
+func TestJailhouse_AnchorOldest_StableAcrossDailyRuns(t *testing.T) {
+	// 2024-01-01 is a Monday
+	epoch := date("2024-01-01").t
+
+	tests := []struct {
+		name           string
+		requirements   *Requirements
+		level          TimeRange
+		representative func(time.Time) bool
+	}{
+		{
+			name:           "first backup of each month",
+			requirements:   NewRequirements().Add(LAST, 2).Add(DAY, 7).Add(MONTH, 6),
+			level:          MONTH,
+			representative: func(t time.Time) bool { return t.Day() == 1 },
+		},
+		{
+			name:           "first backup of each week",
+			requirements:   NewRequirements().Add(DAY, 7).Add(WEEK, 8),
+			level:          WEEK,
+			representative: func(t time.Time) bool { return t.Weekday() == time.Monday },
+		},
+		{
+			name:         "all levels",
+			requirements: NewRequirements().Add(LAST, 2).Add(DAY, 7).Add(WEEK, 4).Add(MONTH, 6).Add(YEAR, 2),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				elements     []TestTimeResource
+				previousKept map[time.Time]bool
+			)
+			for day := 0; day < 400; day++ {
+				// daily backup at 02:00, then prune as a cron job would
+				now := epoch.AddDate(0, 0, day).Add(2 * time.Hour)
+				elements = append(elements, TestTimeResource{t: now})
+
+				x := NewDefaultJailhouse[TestTimeResource]().SetAnchor(AnchorOldest).SetEpoch(epoch)
+				x.AddElements(elements...)
+				x.ApplyRequirementsForDate(*tt.requirements, now)
+
+				kept := make(map[time.Time]bool)
+				for _, k := range x.KeptElements() {
+					kept[k.GetTime()] = true
+					if previousKept != nil && !k.GetTime().Equal(now) && !previousKept[k.GetTime()] {
+						t.Fatalf("day %d: kept %v which was not kept the day before", day, k.GetTime())
+					}
+					if tt.representative != nil && k.HasLevel(tt.level) && !tt.representative(k.GetTime()) {
+						t.Fatalf("day %d: %s representative %v is not the first backup of its range", day, tt.level, k.GetTime())
+					}
+				}
+				previousKept = kept
+
+				elements = elements[:0]
+				for _, k := range x.KeptElements() {
+					elements = append(elements, k.TimeResource)
+				}
+			}
+		})
+	}
+}
+
+func TestJailhouse_EqualTimesAreOrderedByTieBreakKey(t *testing.T) {
+	testDate := time.Date(2024, time.January, 20, 0, 0, 0, 0, time.UTC)
+	sameTime := date("2024-01-10").t
+
+	permutations := [][]File{
+		{{Filename: "b", Time: sameTime}, {Filename: "a", Time: sameTime}, {Filename: "c", Time: sameTime}},
+		{{Filename: "c", Time: sameTime}, {Filename: "b", Time: sameTime}, {Filename: "a", Time: sameTime}},
+		{{Filename: "a", Time: sameTime}, {Filename: "c", Time: sameTime}, {Filename: "b", Time: sameTime}},
+	}
+	for _, files := range permutations {
+		x := NewDefaultJailhouse[File]()
+		x.AddElements(files...)
+		x.ApplyRequirementsForDate(*NewRequirements().Add(LAST, 1), testDate)
+
+		kept := x.KeptElements()
+		assert.Len(t, kept, 1)
+		assert.Equal(t, "a", kept[0].TimeResource.Filename)
+
+		names := make([]string, 0, len(files))
+		for _, e := range x.Elements() {
+			names = append(names, e.TimeResource.Filename)
+		}
+		assert.Equal(t, []string{"a", "b", "c"}, names)
+	}
+}
+
+func TestJailhouse_Observers(t *testing.T) {
+	testDate := time.Date(2024, time.January, 20, 0, 0, 0, 0, time.UTC)
+
+	for _, anchor := range []Anchor{AnchorYoungest, AnchorOldest} {
+		t.Run(anchor.String(), func(t *testing.T) {
+			var (
+				tagged  []string
+				skipped []string
+				freed   []string
+			)
+			x := NewDefaultJailhouse[TestTimeResource]().SetAnchor(anchor).
+				OnTag(func(element *JailhouseTimeResource[TestTimeResource], tag TimeRangeTag) {
+					tagged = append(tagged, element.GetTime().Format("2006-01-02")+" "+tag.String())
+				}).
+				OnSkip(func(element, neighbour *JailhouseTimeResource[TestTimeResource], level TimeRange) {
+					skipped = append(skipped, element.GetTime().Format("2006-01-02")+" "+level.String())
+				}).
+				OnFree(func(element *JailhouseTimeResource[TestTimeResource]) {
+					freed = append(freed, element.GetTime().Format("2006-01-02"))
+				})
+			x.AddElements(
+				date("2023-05-09"),
+				date("2023-02-22"),
+				date("2022-05-11"),
+				date("2022-05-08"),
+				date("2022-02-08"),
+			)
+			x.ApplyRequirementsForDate(*NewRequirements().Add(YEAR, 2), testDate)
+
+			assert.Len(t, tagged, len(x.KeptElements()))
+			assert.Len(t, freed, len(x.FreeElements()))
+			assert.NotEmpty(t, skipped)
+			for _, k := range x.KeptElements() {
+				assert.Contains(t, tagged, k.GetTime().Format("2006-01-02")+" "+k.GetTags()[0].String())
+			}
+		})
+	}
+}
+
+func TestJailhouse_ApplyRequirementsForDateContext(t *testing.T) {
+	testDate := time.Date(2024, time.January, 20, 0, 0, 0, 0, time.UTC)
+	elements := []TestTimeResource{
+		date("2024-01-19"),
+		date("2024-01-18"),
+		date("2023-01-18"),
+	}
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name         string
+		ctx          context.Context
+		requirements *Requirements
+		wantErr      func(error) bool
+		wantKept     int
+	}{
+		{
+			name:         "valid",
+			ctx:          context.Background(),
+			requirements: NewRequirements().Add(LAST, 1).Add(DAY, 1),
+			wantKept:     2,
+		},
+		{
+			name:         "cancelled",
+			ctx:          cancelled,
+			requirements: NewRequirements().Add(LAST, 1),
+			wantErr:      func(err error) bool { return stderrors.Is(err, context.Canceled) },
+		},
+		{
+			name:         "unknown level",
+			ctx:          context.Background(),
+			requirements: NewRequirements().Add(LAST, 1).Add(TimeRange(42), 1),
+			wantErr:      errors.IsNotValid,
+		},
+		{
+			name:         "overflowing count",
+			ctx:          context.Background(),
+			requirements: NewRequirementsFromString("70000 days"),
+			wantErr:      errors.IsNotValid,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			x := NewDefaultJailhouse[TestTimeResource]()
+			x.AddElements(elements...)
+			err := x.ApplyRequirementsForDateContext(tt.ctx, *tt.requirements, testDate)
+			if tt.wantErr != nil {
+				assert.Truef(t, tt.wantErr(err), "unexpected error %v", err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Len(t, x.KeptElements(), tt.wantKept)
+		})
+	}
+}
+
+func TestJailhouse_ApplyRequirementsForDateContext_CancelledWhileApplying(t *testing.T) {
+	testDate := time.Date(2024, time.January, 20, 0, 0, 0, 0, time.UTC)
+	elements := make([]TestTimeResource, 3000)
+	for i := range elements {
+		elements[i] = TestTimeResource{t: testDate.Add(-time.Duration(i+1) * time.Hour)}
+	}
+
+	for _, anchor := range []Anchor{AnchorYoungest, AnchorOldest} {
+		t.Run(anchor.String(), func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			x := NewDefaultJailhouse[TestTimeResource]().SetAnchor(anchor)
+			x.AddElements(elements...)
+			x.OnTag(func(_ *JailhouseTimeResource[TestTimeResource], _ TimeRangeTag) {
+				cancel()
+			})
+			err := x.ApplyRequirementsForDateContext(ctx, *NewRequirements().Add(LAST, 1).Add(HOUR, 100).Add(DAY, 100), testDate)
+			assert.ErrorIs(t, err, context.Canceled)
+			assert.Empty(t, x.KeptElements())
+		})
+	}
+}
+
+func TestJailhouse_Verify(t *testing.T) {
+	testDate := time.Date(2024, time.January, 20, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name         string
+		elements     []TestTimeResource
+		requirements *Requirements
+		tolerance    time.Duration
+		want         []ViolationKind
+	}{
+		{
+			name: "satisfied",
+			elements: []TestTimeResource{
+				date("2024-01-19"),
+				date("2024-01-18"),
+				date("2024-01-17"),
+			},
+			requirements: NewRequirements().Add(DAY, 3),
+			want:         []ViolationKind{},
+		},
+		{
+			name: "too few elements",
+			elements: []TestTimeResource{
+				date("2024-01-19"),
+				date("2024-01-18"),
+			},
+			requirements: NewRequirements().Add(DAY, 3),
+			want:         []ViolationKind{},
+		},
+		{
+			name: "gap",
+			elements: []TestTimeResource{
+				date("2024-01-19"),
+				date("2024-01-18"),
+				date("2024-01-12"),
+			},
+			requirements: NewRequirements().Add(DAY, 3),
+			want:         []ViolationKind{ViolationGap},
+		},
+		{
+			name: "late backup within tolerance",
+			elements: []TestTimeResource{
+				{t: time.Date(2024, time.January, 19, 2, 0, 0, 0, time.UTC)},
+				{t: time.Date(2024, time.January, 18, 1, 30, 0, 0, time.UTC)},
+			},
+			requirements: NewRequirements().Add(DAY, 2),
+			tolerance:    time.Hour,
+			want:         []ViolationKind{},
+		},
+		{
+			name: "late backup beyond tolerance",
+			elements: []TestTimeResource{
+				{t: time.Date(2024, time.January, 19, 2, 0, 0, 0, time.UTC)},
+				{t: time.Date(2024, time.January, 18, 1, 30, 0, 0, time.UTC)},
+			},
+			requirements: NewRequirements().Add(DAY, 2),
+			tolerance:    10 * time.Minute,
+			want:         []ViolationKind{ViolationGap},
+		},
+		{
+			name: "unjustified",
+			elements: []TestTimeResource{
+				date("2024-01-19"),
+				date("2024-01-18"),
+				date("2024-01-17"),
+			},
+			requirements: NewRequirements().Add(LAST, 2),
+			want:         []ViolationKind{ViolationUnjustified},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			x := NewDefaultJailhouse[TestTimeResource]()
+			x.AddElements(tt.elements...)
+			kinds := make([]ViolationKind, 0)
+			for _, v := range x.Verify(*tt.requirements, testDate, tt.tolerance) {
+				kinds = append(kinds, v.Kind)
+			}
+			assert.Equal(t, tt.want, kinds)
+		})
+	}
+}
+
+func FuzzJailhouse_Verify(f *testing.F) {
+	f.Add(int64(1), uint16(50), false)
+	f.Add(int64(2), uint16(500), false)
+	f.Add(int64(3), uint16(500), true)
+	f.Add(int64(4), uint16(2000), true)
+	f.Add(int64(5), uint16(0), false)
+
+	referenceDate := time.Date(2024, time.January, 20, 0, 0, 0, 0, time.UTC)
+	f.Fuzz(func(t *testing.T, seed int64, count uint16, anchorOldest bool) {
+		rnd := rand.New(rand.NewSource(seed))
+
+		// random timeline over the last three years, clustered like real backups with gaps
+		elements := make([]TestTimeResource, 0, count)
+		for i := 0; i < int(count); i++ {
+			age := time.Duration(rnd.Int63n(int64(3 * 365 * 24 * time.Hour)))
+			elements = append(elements, TestTimeResource{t: referenceDate.Add(-age)})
+		}
+		reqs := NewRequirements()
+		for _, level := range []TimeRange{LAST, HOUR, DAY, WEEK, MONTH, YEAR} {
+			reqs.Add(level, int8(rnd.Intn(15)))
+		}
+		anchor := AnchorYoungest
+		if anchorOldest {
+			anchor = AnchorOldest
+		}
+
+		x := NewDefaultJailhouse[TestTimeResource]().SetAnchor(anchor).SetEpoch(referenceDate.AddDate(-5, 0, 0))
+		x.AddElements(elements...)
+		for _, v := range x.Verify(*reqs, referenceDate, time.Hour) {
+			if v.Kind == ViolationUnfilledLevel {
+				t.Fatalf("%v", v)
+			}
+		}
+
+		// applying the requirements to what they kept must keep everything again
+		kept := make([]TestTimeResource, 0)
+		for _, k := range x.KeptElements() {
+			kept = append(kept, k.TimeResource)
+		}
+		y := NewDefaultJailhouse[TestTimeResource]().SetAnchor(anchor).SetEpoch(referenceDate.AddDate(-5, 0, 0))
+		y.AddElements(kept...)
+		for _, v := range y.Verify(*reqs, referenceDate, time.Hour) {
+			if v.Kind == ViolationUnfilledLevel || v.Kind == ViolationUnjustified {
+				t.Fatalf("%v", v)
+			}
+		}
+
+		// independently of the selection, a daily series with jitter below the tolerance satisfies one element per
+		// day, and without one of its days the neighbours of that day form the only gap
+		days := 3 + rnd.Intn(20)
+		series := make([]TestTimeResource, days)
+		for i := range series {
+			jitter := time.Duration(rnd.Int63n(int64(time.Hour))) - 30*time.Minute
+			series[i] = TestTimeResource{t: referenceDate.AddDate(0, 0, -i-1).Add(12*time.Hour + jitter)}
+		}
+		dailyReqs := NewRequirements().Add(DAY, int8(days))
+		complete := NewDefaultJailhouse[TestTimeResource]().SetAnchor(anchor).SetEpoch(referenceDate.AddDate(-5, 0, 0))
+		complete.AddElements(series...)
+		if violations := complete.Verify(*dailyReqs, referenceDate, time.Hour); len(violations) > 0 {
+			t.Fatalf("complete series: %v", violations)
+		}
+
+		missing := 1 + rnd.Intn(days-2)
+		incomplete := NewDefaultJailhouse[TestTimeResource]().SetAnchor(anchor).SetEpoch(referenceDate.AddDate(-5, 0, 0))
+		incomplete.AddElements(append(append([]TestTimeResource{}, series[:missing]...), series[missing+1:]...)...)
+		violations := incomplete.Verify(*dailyReqs, referenceDate, time.Hour)
+		if len(violations) != 1 || violations[0].Kind != ViolationGap {
+			t.Fatalf("series without day %d: %v", missing, violations)
+		}
+		gap := violations[0].Elements
+		if !gap[0].GetTime().Equal(series[missing-1].t) || !gap[1].GetTime().Equal(series[missing+1].t) {
+			t.Fatalf("series without day %d: gap between %v and %v", missing, gap[0].GetTime(), gap[1].GetTime())
+		}
+	})
+}
+
 func assertSameElements(t *testing.T, expected, seen []*JailhouseTimeResource[TestTimeResource]) {
 	// sort both lists
 	sort.SliceStable(expected, func(i, j int) bool {
@@ -0,0 +1,86 @@
+package keep
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// LevelReport summarizes how well a single level of the applied Requirements is met.
+type LevelReport[T TimeResource] struct {
+	Level     TimeRange `json:"level"`
+	Requested uint16    `json:"requested"`
+	Kept      int       `json:"kept"`
+	// Shortfall is the number of requested elements that could not be kept because there is not enough history.
+	Shortfall int `json:"shortfall"`
+	// Youngest and Oldest are the times of the youngest and oldest element kept for this level, zero if none.
+	Youngest time.Time     `json:"youngest"`
+	Oldest   time.Time     `json:"oldest"`
+	Span     time.Duration `json:"-"`
+	// OldestElement is the oldest element kept for this level, nil if none.
+	OldestElement *JailhouseTimeResource[T] `json:"oldestElement,omitempty"`
+}
+
+// MarshalJSON encodes the LevelReport with its Span in seconds as spanSeconds, raw nanoseconds are hard to read.
+func (x LevelReport[T]) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Level         TimeRange                 `json:"level"`
+		Requested     uint16                    `json:"requested"`
+		Kept          int                       `json:"kept"`
+		Shortfall     int                       `json:"shortfall"`
+		Youngest      time.Time                 `json:"youngest"`
+		Oldest        time.Time                 `json:"oldest"`
+		SpanSeconds   float64                   `json:"spanSeconds"`
+		OldestElement *JailhouseTimeResource[T] `json:"oldestElement,omitempty"`
+	}{
+		Level:         x.Level,
+		Requested:     x.Requested,
+		Kept:          x.Kept,
+		Shortfall:     x.Shortfall,
+		Youngest:      x.Youngest,
+		Oldest:        x.Oldest,
+		SpanSeconds:   x.Span.Seconds(),
+		OldestElement: x.OldestElement,
+	})
+}
+
+func (x LevelReport[T]) String() string {
+	if x.Kept == 0 {
+		return fmt.Sprintf("%s: 0/%d", x.Level, x.Requested)
+	}
+	result := fmt.Sprintf("%s: %d/%d from %s to %s (%s)", x.Level, x.Kept, x.Requested, x.Oldest.Format(time.RFC3339), x.Youngest.Format(time.RFC3339), x.Span.Round(time.Second))
+	if x.Shortfall > 0 {
+		result += fmt.Sprintf(", %d short", x.Shortfall)
+	}
+	return result
+}
+
+// Report returns a LevelReport for every level of the Requirements last applied, ordered from the shortest to the
+// longest TimeRange.
+func (x *Jailhouse[T]) Report() []LevelReport[T] {
+	reports := make([]LevelReport[T], 0)
+	for _, level := range x.GetLevels() {
+		requested := x.requirements.Get(level)
+		if requested == 0 {
+			continue
+		}
+
+		kept := x.KeptElementsByLevel(level)
+		report := LevelReport[T]{
+			Level:     level,
+			Requested: requested,
+			Kept:      len(kept),
+		}
+		if report.Kept < int(requested) {
+			report.Shortfall = int(requested) - report.Kept
+		}
+		if len(kept) > 0 {
+			report.OldestElement = kept[len(kept)-1]
+			report.Youngest = kept[0].GetTime()
+			report.Oldest = report.OldestElement.GetTime()
+			report.Span = report.Youngest.Sub(report.Oldest)
+		}
+		reports = append(reports, report)
+	}
+	return reports
+}
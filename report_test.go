@@ -0,0 +1,56 @@
+package keep
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJailhouse_Report(t *testing.T) {
+	testDate := time.Date(2024, time.January, 20, 0, 0, 0, 0, time.UTC)
+
+	x := NewDefaultJailhouse[TestTimeResource]()
+	x.AddElements(
+		date("2024-01-19"), // LAST-1
+		date("2024-01-18"), // DAY-1
+		date("2024-01-17"), // DAY-2
+		date("2024-01-10"), // WEEK-1
+	)
+	x.ApplyRequirementsForDate(*NewRequirements().Add(LAST, 1).Add(DAY, 2).Add(WEEK, 3).Add(YEAR, 0), testDate)
+
+	reports := x.Report()
+	assert.Len(t, reports, 3)
+
+	assert.Equal(t, LAST, reports[0].Level)
+	assert.Equal(t, 1, reports[0].Kept)
+	assert.Equal(t, 0, reports[0].Shortfall)
+
+	assert.Equal(t, DAY, reports[1].Level)
+	assert.Equal(t, uint16(2), reports[1].Requested)
+	assert.Equal(t, 2, reports[1].Kept)
+	assert.Equal(t, date("2024-01-18").t, reports[1].Youngest)
+	assert.Equal(t, date("2024-01-17").t, reports[1].Oldest)
+	assert.Equal(t, 24*time.Hour, reports[1].Span)
+	assert.Equal(t, date("2024-01-17"), reports[1].OldestElement.TimeResource)
+
+	assert.Equal(t, WEEK, reports[2].Level)
+	assert.Equal(t, 1, reports[2].Kept)
+	assert.Equal(t, 2, reports[2].Shortfall)
+	assert.Equal(t, "WEEK: 1/3 from 2024-01-10T00:00:00Z to 2024-01-10T00:00:00Z (0s), 2 short", reports[2].String())
+}
+
+func TestLevelReport_JSON(t *testing.T) {
+	report := LevelReport[TestTimeResource]{
+		Level:     MONTH,
+		Requested: 4,
+		Shortfall: 4,
+		Span:      36 * time.Hour,
+	}
+	data, err := json.Marshal(report)
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), `"level":"MONTH"`)
+	assert.Contains(t, string(data), `"spanSeconds":129600`)
+	assert.NotContains(t, string(data), `oldestElement`)
+}
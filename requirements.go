@@ -1,15 +1,23 @@
 package keep
 
 import (
+	"encoding/json"
 	"fmt"
+	"math"
 	"regexp"
 	"strconv"
 	"strings"
+	"unicode"
+
+	"github.com/juju/errors"
+	"golang.org/x/exp/slices"
 )
 
 // Requirements define which elements in an input slice should be kept
 type Requirements struct {
 	ranges map[TimeRange]uint16
+	// overflows holds the TimeRanges whose count left the range of uint16, see Validate
+	overflows map[TimeRange]bool
 }
 
 // NewRequirements creates a new empty Requirement definition.
@@ -28,39 +36,54 @@ func NewRequirementsFromMap(data map[TimeRange]uint16) *Requirements {
 	return r
 }
 
+// requirementPattern matches a single requirement like "7 days".
+var requirementPattern = regexp.MustCompile(`(?i)(\d+)\s+(last|seconds?|minutes?|hours?|days?|weeks?|months?|quarters?|years?)`)
+
 func NewRequirementsFromString(source string) *Requirements {
 	r := NewRequirements()
-	re := regexp.MustCompile(`(?i)(\d+)\s+(last|seconds?|minutes?|hours?|days?|weeks?|months?|quarters?|years?)`)
-	matches := re.FindAllStringSubmatch(source, -1)
+	matches := requirementPattern.FindAllStringSubmatch(source, -1)
 	for _, match := range matches {
 		num, err := strconv.Atoi(match[1])
 		if err != nil {
-			continue
+			// too many digits for an int, surely too many for uint16 as well
+			num = math.MaxUint16 + 1
 		}
 		switch strings.ToLower(match[2]) {
 		case "last":
-			r.ranges[LAST] += uint16(num)
+			r.add(LAST, num)
 		case "second", "seconds":
-			r.ranges[SECOND] += uint16(num)
+			r.add(SECOND, num)
 		case "minute", "minutes":
-			r.ranges[MINUTE] += uint16(num)
+			r.add(MINUTE, num)
 		case "hour", "hours":
-			r.ranges[HOUR] += uint16(num)
+			r.add(HOUR, num)
 		case "day", "days":
-			r.ranges[DAY] += uint16(num)
+			r.add(DAY, num)
 		case "week", "weeks":
-			r.ranges[WEEK] += uint16(num)
+			r.add(WEEK, num)
 		case "month", "months":
-			r.ranges[MONTH] += uint16(num)
+			r.add(MONTH, num)
 		case "quarter", "quarters":
-			r.ranges[QUARTER] += uint16(num)
+			r.add(QUARTER, num)
 		case "year", "years":
-			r.ranges[YEAR] += uint16(num)
+			r.add(YEAR, num)
 		}
 	}
 	return r
 }
 
+// ParseRequirements works like NewRequirementsFromString, but fails for text that is not a requirement instead of
+// ignoring it, e.g. for "7 fortnights". Requirements may be separated by whitespace and commas.
+func ParseRequirements(source string) (*Requirements, error) {
+	unknown := strings.FieldsFunc(requirementPattern.ReplaceAllString(source, ","), func(r rune) bool {
+		return r == ',' || unicode.IsSpace(r)
+	})
+	if len(unknown) > 0 {
+		return nil, errors.NotValidf("%q in requirements %q", strings.Join(unknown, " "), source)
+	}
+	return NewRequirementsFromString(source), nil
+}
+
 // IsEmpty is true iff no files should be kept.
 func (x Requirements) IsEmpty() bool {
 	for _, v := range x.ranges {
@@ -81,12 +104,38 @@ func (x Requirements) Get(timeRange TimeRange) uint16 {
 
 // Add adds a number of required elements for a given TimeRange.
 func (x *Requirements) Add(timeRange TimeRange, value int8) *Requirements {
-	if _, ok := x.ranges[timeRange]; !ok {
-		x.ranges[timeRange] = 0
+	x.add(timeRange, int(value))
+	return x
+}
+
+func (x *Requirements) add(timeRange TimeRange, value int) {
+	count := int(x.ranges[timeRange]) + value
+	if count < 0 || count > math.MaxUint16 {
+		if x.overflows == nil {
+			x.overflows = make(map[TimeRange]bool)
+		}
+		x.overflows[timeRange] = true
 	}
-	x.ranges[timeRange] = uint16(int(x.ranges[timeRange]) + int(value))
+	x.ranges[timeRange] = uint16(count)
+}
 
-	return x
+// Validate returns an error if the Requirement contains invalid TimeRanges or counts that overflowed while adding.
+func (x Requirements) Validate() error {
+	timeRanges := make([]TimeRange, 0, len(x.ranges))
+	for timeRange := range x.ranges {
+		timeRanges = append(timeRanges, timeRange)
+	}
+	slices.Sort(timeRanges)
+
+	for _, timeRange := range timeRanges {
+		if _, err := ParseTimeRange(timeRange.String()); err != nil {
+			return errors.NotValidf("level %s", timeRange)
+		}
+		if x.overflows[timeRange] {
+			return errors.NotValidf("overflowing count for %s", timeRange)
+		}
+	}
+	return nil
 }
 
 // DeepCopy returns a Requirement copy with the same properties.
@@ -95,6 +144,12 @@ func (x Requirements) DeepCopy() Requirements {
 	for key, value := range x.ranges {
 		r.ranges[key] = value
 	}
+	for key, value := range x.overflows {
+		if r.overflows == nil {
+			r.overflows = make(map[TimeRange]bool)
+		}
+		r.overflows[key] = value
+	}
 	return *r
 }
 
@@ -108,3 +163,20 @@ func (x Requirements) String() string {
 	}
 	return strings.Join(elems, ", ")
 }
+
+// MarshalJSON encodes the Requirement as object with the TimeRange names as keys, e.g. {"DAY":14,"WEEK":12}.
+func (x Requirements) MarshalJSON() ([]byte, error) {
+	return json.Marshal(x.ranges)
+}
+
+// UnmarshalJSON decodes a Requirement encoded by MarshalJSON.
+func (x *Requirements) UnmarshalJSON(data []byte) error {
+	ranges := make(map[TimeRange]uint16)
+	err := json.Unmarshal(data, &ranges)
+	if err != nil {
+		return errors.Annotate(err, "decoding requirements")
+	}
+	x.ranges = ranges
+	x.overflows = nil
+	return nil
+}
@@ -1,8 +1,10 @@
 package keep
 
 import (
+	"encoding/json"
 	"testing"
 
+	"github.com/juju/errors"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -161,3 +163,95 @@ func TestRequirements_Get(t *testing.T) {
 		})
 	}
 }
+
+func TestRequirements_Validate(t *testing.T) {
+	tests := []struct {
+		name         string
+		requirements *Requirements
+		wantErr      bool
+	}{
+		{
+			name:         "valid",
+			requirements: NewRequirementsFromString("3 last, 12 months"),
+		},
+		{
+			name:         "invalid level",
+			requirements: NewRequirementsFromMap(map[TimeRange]uint16{TimeRange(-1): 1}),
+			wantErr:      true,
+		},
+		{
+			name:         "count too big",
+			requirements: NewRequirementsFromString("65536 hours"),
+			wantErr:      true,
+		},
+		{
+			name:         "count too big in sum",
+			requirements: NewRequirementsFromString("65535 hours, 1 hour"),
+			wantErr:      true,
+		},
+		{
+			name:         "count below zero",
+			requirements: NewRequirements().Add(DAY, -1),
+			wantErr:      true,
+		},
+		{
+			name:         "count too big for int",
+			requirements: NewRequirementsFromString("99999999999999999999 years"),
+			wantErr:      true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.requirements.Validate()
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			// copies keep the result
+			assert.Equal(t, err != nil, tt.requirements.DeepCopy().Validate() != nil)
+		})
+	}
+}
+
+func TestParseRequirements(t *testing.T) {
+	tests := []struct {
+		name    string
+		source  string
+		want    map[TimeRange]uint16
+		wantErr bool
+	}{
+		{name: "separated by commas", source: "3 last, 12 months", want: map[TimeRange]uint16{LAST: 3, MONTH: 12}},
+		{name: "separated by spaces", source: " 1 Day 2 weeks ", want: map[TimeRange]uint16{DAY: 1, WEEK: 2}},
+		{name: "empty", source: "", want: map[TimeRange]uint16{}},
+		{name: "unknown level", source: "7 fortnights", wantErr: true},
+		{name: "unknown part", source: "7 days, 4 weeks please", wantErr: true},
+		{name: "level with suffix", source: "7 daysx", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseRequirements(tt.source)
+			if tt.wantErr {
+				assert.True(t, errors.IsNotValid(err), err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, NewRequirementsFromMap(tt.want), got)
+		})
+	}
+}
+
+func TestRequirements_JSON(t *testing.T) {
+	asrt := assert.New(t)
+
+	r := NewRequirementsFromString("10 last, 14 days, 12 weeks")
+	data, err := json.Marshal(r)
+	asrt.NoError(err)
+	asrt.JSONEq(`{"LAST":10,"DAY":14,"WEEK":12}`, string(data))
+
+	var decoded Requirements
+	asrt.NoError(json.Unmarshal(data, &decoded))
+	asrt.Equal(r.String(), decoded.String())
+
+	asrt.Error(json.Unmarshal([]byte(`{"FORTNIGHT":2}`), &decoded))
+}
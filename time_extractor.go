@@ -0,0 +1,207 @@
+package keep
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/juju/errors"
+)
+
+// TimeExtractor parses times from names or paths, e.g. from "pg-2024-03-01T0200.sql.gz" or "2024/03/01/dump.tar".
+type TimeExtractor struct {
+	pattern  *regexp.Regexp
+	location *time.Location
+}
+
+// timeExtractorGroups are the named groups a TimeExtractor evaluates, all of them are optional.
+var timeExtractorGroups = []string{"year", "month", "day", "hour", "minute", "second"}
+
+// goLayoutTokens maps the elements of a Go time layout to regular expressions, longest first.
+var goLayoutTokens = []struct {
+	token string
+	expr  string
+}{
+	{"2006", `(?P<year>\d{4})`},
+	{"January", `(?P<monthname>[A-Za-z]+)`},
+	{"Jan", `(?P<monthname>[A-Za-z]{3})`},
+	{"01", `(?P<month>\d{2})`},
+	{"02", `(?P<day>\d{2})`},
+	{"15", `(?P<hour>\d{2})`},
+	{"04", `(?P<minute>\d{2})`},
+	{"05", `(?P<second>\d{2})`},
+	{"06", `(?P<shortyear>\d{2})`},
+}
+
+// unsupportedLayoutTokens are the elements of a Go time layout a TimeExtractor cannot evaluate. Fractional seconds
+// are detected separately, see layoutFractionLength.
+var unsupportedLayoutTokens = []string{
+	"-07:00:00", "Z07:00:00", "-070000", "Z070000", "-07:00", "Z07:00", "-0700", "Z0700", "-07", "Z07",
+	"Monday", "Mon", "MST", "PM", "pm", "002", "__2", "_2", "1", "2", "3", "4", "5",
+}
+
+// strftimeDirectives maps strftime directives to regular expressions.
+var strftimeDirectives = map[byte]string{
+	'Y': `(?P<year>\d{4})`,
+	'y': `(?P<shortyear>\d{2})`,
+	'm': `(?P<month>\d{2})`,
+	'b': `(?P<monthname>[A-Za-z]{3})`,
+	'B': `(?P<monthname>[A-Za-z]+)`,
+	'd': `(?P<day>\d{2})`,
+	'H': `(?P<hour>\d{2})`,
+	'M': `(?P<minute>\d{2})`,
+	'S': `(?P<second>\d{2})`,
+	'%': `%`,
+}
+
+// NewTimeExtractorFromLayout creates a TimeExtractor finding a Go time layout like "2006-01-02T1504" anywhere in a
+// path. Times are read in the given location, time.Local if nil.
+func NewTimeExtractorFromLayout(layout string, location *time.Location) (*TimeExtractor, error) {
+	var expr strings.Builder
+	for i := 0; i < len(layout); {
+		if length := layoutFractionLength(layout[i:]); length > 0 {
+			return nil, errors.NotSupportedf("time layout element %q", layout[i:i+length])
+		}
+		// the longest element wins, e.g. "2006" over "2" and "002" over "02"
+		var (
+			supported   string
+			expression  string
+			unsupported string
+		)
+		for _, token := range goLayoutTokens {
+			if len(token.token) > len(supported) && strings.HasPrefix(layout[i:], token.token) {
+				supported, expression = token.token, token.expr
+			}
+		}
+		for _, token := range unsupportedLayoutTokens {
+			if len(token) > len(unsupported) && strings.HasPrefix(layout[i:], token) {
+				unsupported = token
+			}
+		}
+		switch {
+		case len(unsupported) > len(supported):
+			return nil, errors.NotSupportedf("time layout element %q", unsupported)
+		case supported != "":
+			expr.WriteString(expression)
+			i += len(supported)
+		default:
+			expr.WriteString(regexp.QuoteMeta(layout[i : i+1]))
+			i++
+		}
+	}
+	return NewTimeExtractorFromRegexp(expr.String(), location)
+}
+
+// layoutFractionLength returns the length of the fractional seconds element like ".000" or ",999" layout starts with,
+// 0 if there is none. As in package time, the element must not be followed by another digit.
+func layoutFractionLength(layout string) int {
+	if len(layout) < 2 || (layout[0] != '.' && layout[0] != ',') || (layout[1] != '0' && layout[1] != '9') {
+		return 0
+	}
+	length := 2
+	for length < len(layout) && layout[length] == layout[1] {
+		length++
+	}
+	if length < len(layout) && layout[length] >= '0' && layout[length] <= '9' {
+		return 0
+	}
+	return length
+}
+
+// NewTimeExtractorFromStrftime creates a TimeExtractor finding a strftime pattern like "%Y-%m-%dT%H%M" anywhere in a
+// path. Times are read in the given location, time.Local if nil.
+func NewTimeExtractorFromStrftime(pattern string, location *time.Location) (*TimeExtractor, error) {
+	var expr strings.Builder
+	for i := 0; i < len(pattern); i++ {
+		if pattern[i] != '%' {
+			expr.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+			continue
+		}
+		if i+1 >= len(pattern) {
+			return nil, errors.NotValidf("strftime pattern %q ending in %%", pattern)
+		}
+		directive, ok := strftimeDirectives[pattern[i+1]]
+		if !ok {
+			return nil, errors.NotSupportedf("strftime directive %%%c", pattern[i+1])
+		}
+		expr.WriteString(directive)
+		i++
+	}
+	return NewTimeExtractorFromRegexp(expr.String(), location)
+}
+
+// NewTimeExtractorFromRegexp creates a TimeExtractor from a regular expression with named groups year, month, day,
+// hour, minute, and second, e.g. `(?P<year>\d{4})/(?P<month>\d{2})`. Missing groups default to the start of the
+// period. Times are read in the given location, time.Local if nil.
+func NewTimeExtractorFromRegexp(expr string, location *time.Location) (*TimeExtractor, error) {
+	pattern, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, errors.Annotatef(err, "compiling time pattern %q", expr)
+	}
+	if pattern.SubexpIndex("year") < 0 && pattern.SubexpIndex("shortyear") < 0 {
+		return nil, errors.NotValidf("time pattern %q without year", expr)
+	}
+	if location == nil {
+		location = time.Local
+	}
+	return &TimeExtractor{
+		pattern:  pattern,
+		location: location,
+	}, nil
+}
+
+// Extract returns the time found in the given path. Path separators are normalized to slashes before matching.
+func (x *TimeExtractor) Extract(path string) (time.Time, error) {
+	path = strings.ReplaceAll(path, "\\", "/")
+	match := x.pattern.FindStringSubmatch(path)
+	if match == nil {
+		return time.Time{}, errors.NotFoundf("time in %q", path)
+	}
+
+	values := map[string]int{
+		"month": 1,
+		"day":   1,
+	}
+	for _, group := range timeExtractorGroups {
+		index := x.pattern.SubexpIndex(group)
+		if index < 0 || match[index] == "" {
+			continue
+		}
+		value, err := strconv.Atoi(match[index])
+		if err != nil {
+			return time.Time{}, errors.Annotatef(err, "parsing %s in %q", group, path)
+		}
+		values[group] = value
+	}
+	if index := x.pattern.SubexpIndex("shortyear"); index >= 0 && match[index] != "" {
+		value, err := strconv.Atoi(match[index])
+		if err != nil {
+			return time.Time{}, errors.Annotatef(err, "parsing year in %q", path)
+		}
+		values["year"] = 2000 + value
+	}
+	if index := x.pattern.SubexpIndex("monthname"); index >= 0 && match[index] != "" {
+		month, err := parseMonthName(match[index])
+		if err != nil {
+			return time.Time{}, err
+		}
+		values["month"] = int(month)
+	}
+
+	// time.Date normalizes out of range values, e.g. February 30 to March 1, so those read back differently
+	t := time.Date(values["year"], time.Month(values["month"]), values["day"], values["hour"], values["minute"], values["second"], 0, x.location)
+	if t.Year() != values["year"] || int(t.Month()) != values["month"] || t.Day() != values["day"] || t.Hour() != values["hour"] || t.Minute() != values["minute"] || t.Second() != values["second"] {
+		return time.Time{}, errors.NotValidf("time in %q", path)
+	}
+	return t, nil
+}
+
+func parseMonthName(name string) (time.Month, error) {
+	for month := time.January; month <= time.December; month++ {
+		if strings.EqualFold(month.String(), name) || strings.EqualFold(month.String()[:3], name) {
+			return month, nil
+		}
+	}
+	return 0, errors.NotValidf("month %q", name)
+}
@@ -0,0 +1,137 @@
+package keep
+
+import (
+	"testing"
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTimeExtractor_Extract(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	assert.NoError(t, err)
+
+	fromLayout := func(layout string) func() (*TimeExtractor, error) {
+		return func() (*TimeExtractor, error) { return NewTimeExtractorFromLayout(layout, time.UTC) }
+	}
+	fromStrftime := func(pattern string) func() (*TimeExtractor, error) {
+		return func() (*TimeExtractor, error) { return NewTimeExtractorFromStrftime(pattern, time.UTC) }
+	}
+	fromRegexp := func(expr string) func() (*TimeExtractor, error) {
+		return func() (*TimeExtractor, error) { return NewTimeExtractorFromRegexp(expr, time.UTC) }
+	}
+
+	tests := []struct {
+		name      string
+		extractor func() (*TimeExtractor, error)
+		path      string
+		want      time.Time
+		wantErr   func(error) bool
+	}{
+		{
+			name:      "go layout in filename",
+			extractor: fromLayout("2006-01-02T1504"),
+			path:      "pg-2024-03-01T0200.sql.gz",
+			want:      time.Date(2024, time.March, 1, 2, 0, 0, 0, time.UTC),
+		},
+		{
+			name:      "go layout in path",
+			extractor: fromLayout("2006/01/02"),
+			path:      "2024/03/01/dump.tar",
+			want:      time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:      "go layout with month name",
+			extractor: fromLayout("02Jan06"),
+			path:      "backup-17Feb23.zip",
+			want:      time.Date(2023, time.February, 17, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:      "strftime",
+			extractor: fromStrftime("%Y%m%d-%H%M%S"),
+			path:      "db-20240301-020304.sql",
+			want:      time.Date(2024, time.March, 1, 2, 3, 4, 0, time.UTC),
+		},
+		{
+			name:      "regexp",
+			extractor: fromRegexp(`(?P<year>\d{4})/(?P<month>\d{2})/(?P<day>\d{2})`),
+			path:      `2024\03\01\dump.tar`,
+			want:      time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "location",
+			extractor: func() (*TimeExtractor, error) {
+				return NewTimeExtractorFromLayout("2006-01-02", berlin)
+			},
+			path: "2024-03-01.tar",
+			want: time.Date(2024, time.March, 1, 0, 0, 0, 0, berlin),
+		},
+		{
+			name:      "no match",
+			extractor: fromLayout("2006-01-02"),
+			path:      "dump.tar",
+			wantErr:   errors.IsNotFound,
+		},
+		{
+			name:      "invalid date",
+			extractor: fromLayout("2006-01-02"),
+			path:      "2024-13-01.tar",
+			wantErr:   errors.IsNotValid,
+		},
+		{
+			name:      "day not in month",
+			extractor: fromLayout("2006-01-02"),
+			path:      "2024-02-30.tar",
+			wantErr:   errors.IsNotValid,
+		},
+		{
+			name:      "leap second",
+			extractor: fromStrftime("%Y%m%d-%H%M%S"),
+			path:      "db-20240301-020360.sql",
+			wantErr:   errors.IsNotValid,
+		},
+		{
+			name:      "leap day",
+			extractor: fromLayout("2006-01-02"),
+			path:      "2024-02-29.tar",
+			want:      time.Date(2024, time.February, 29, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:      "go layout with literal digits",
+			extractor: fromLayout("v0-2006-01-02"),
+			path:      "v0-2024-03-01.tar",
+			want:      time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			x, err := tt.extractor()
+			assert.NoError(t, err)
+
+			got, err := x.Extract(tt.path)
+			if tt.wantErr != nil {
+				assert.Truef(t, tt.wantErr(err), "unexpected error %v", err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.True(t, tt.want.Equal(got), "expected %v, got %v", tt.want, got)
+		})
+	}
+}
+
+func TestNewTimeExtractor_Invalid(t *testing.T) {
+	_, err := NewTimeExtractorFromRegexp(`(?P<month>\d{2})`, nil)
+	assert.True(t, errors.IsNotValid(err))
+
+	_, err = NewTimeExtractorFromRegexp(`(`, nil)
+	assert.Error(t, err)
+
+	_, err = NewTimeExtractorFromStrftime("%Y-%q", nil)
+	assert.True(t, errors.IsNotSupported(err))
+
+	for _, layout := range []string{"2006-1-2", "2006-01-_2", "2006-002", "Mon 2006-01-02", "2006-01-02 03PM", "2006-01-02 15:04 MST", "2006-01-02T15:04:05-07:00", "2006-01-02T15:04:05Z0700", "2006-01-02 15:04:05.000", "2006-01-02 15:04:05,999999"} {
+		_, err = NewTimeExtractorFromLayout(layout, nil)
+		assert.Truef(t, errors.IsNotSupported(err), "layout %q: unexpected error %v", layout, err)
+	}
+}
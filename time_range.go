@@ -1,6 +1,6 @@
 package keep
 
-//go:generate go-enum -f "$GOFILE" --noprefix --names --nocase --mustparse
+//go:generate go-enum -f "$GOFILE" --noprefix --names --nocase --mustparse --marshal
 
 /*
 	 ENUM(
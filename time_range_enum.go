@@ -132,3 +132,19 @@ func MustParseTimeRange(name string) TimeRange {
 	}
 	return val
 }
+
+// MarshalText implements the text marshaller method.
+func (x TimeRange) MarshalText() ([]byte, error) {
+	return []byte(x.String()), nil
+}
+
+// UnmarshalText implements the text unmarshaller method.
+func (x *TimeRange) UnmarshalText(text []byte) error {
+	name := string(text)
+	tmp, err := ParseTimeRange(name)
+	if err != nil {
+		return err
+	}
+	*x = tmp
+	return nil
+}
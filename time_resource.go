@@ -6,3 +6,9 @@ import "time"
 type TimeResource interface {
 	GetTime() time.Time
 }
+
+// TieBreaker can optionally be implemented by a TimeResource to order elements sharing the same time.
+// Elements with equal times are sorted by ascending TieBreakKey, so results are reproducible between runs.
+type TieBreaker interface {
+	TieBreakKey() string
+}
@@ -0,0 +1,198 @@
+package keep
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"math"
+	"strings"
+	"time"
+)
+
+// timelineColors maps every TimeRange to the color used for its kept elements.
+var timelineColors = map[TimeRange]string{
+	LAST:      "#1f77b4",
+	SECOND:    "#aec7e8",
+	MINUTE:    "#17becf",
+	HOUR:      "#9467bd",
+	DAY:       "#2ca02c",
+	WEEK:      "#bcbd22",
+	MONTH:     "#ff7f0e",
+	QUARTER:   "#8c564b",
+	YEAR:      "#d62728",
+	DECADE:    "#e377c2",
+	CENTURY:   "#7f7f7f",
+	MILLENIUM: "#000000",
+}
+
+// timelineSymbols maps every TimeRange to the character used for its kept elements in ASCII timelines.
+var timelineSymbols = map[TimeRange]byte{
+	LAST:      'L',
+	SECOND:    's',
+	MINUTE:    'm',
+	HOUR:      'h',
+	DAY:       'D',
+	WEEK:      'W',
+	MONTH:     'M',
+	QUARTER:   'Q',
+	YEAR:      'Y',
+	DECADE:    'X',
+	CENTURY:   'C',
+	MILLENIUM: 'K',
+}
+
+const timelineFreeColor = "#cccccc"
+
+// RenderASCII draws the elements on a timeline width characters wide, oldest left, reference date right.
+// Kept elements are shown by the symbol of their first level, free ones as '-'. A second line marks the oldest
+// element of every level with '|'. The time axis is logarithmic in the age of the elements, so recent elements
+// are not squashed into a single column by a long history.
+func (x *Jailhouse[T]) RenderASCII(width int) string {
+	if width < 10 {
+		width = 10
+	}
+	timeline := []byte(strings.Repeat(" ", width))
+	boundaries := []byte(strings.Repeat(" ", width))
+
+	position := x.timelinePosition()
+	for _, element := range x.elements {
+		column := int(math.Round(position(element.GetTime()) * float64(width-1)))
+		switch {
+		case !element.IsFree():
+			timeline[column] = timelineSymbols[element.GetTags()[0].TimeRange]
+		case timeline[column] == ' ':
+			timeline[column] = '-'
+		}
+	}
+
+	legend := make([]string, 0)
+	for _, level := range x.GetLevels() {
+		kept := x.KeptElementsByLevel(level)
+		if len(kept) == 0 {
+			continue
+		}
+		column := int(math.Round(position(kept[len(kept)-1].GetTime()) * float64(width-1)))
+		boundaries[column] = '|'
+		legend = append(legend, fmt.Sprintf("%c=%s (%d)", timelineSymbols[level], level, len(kept)))
+	}
+	legend = append(legend, fmt.Sprintf("-=free (%d)", len(x.FreeElements())))
+
+	oldest, youngest := x.timelineRange()
+	header := oldest.Format("2006-01-02")
+	footer := youngest.Format("2006-01-02")
+	padding := width - len(header) - len(footer)
+	if padding < 1 {
+		padding = 1
+	}
+
+	return strings.Join([]string{
+		header + strings.Repeat(" ", padding) + footer,
+		string(timeline),
+		strings.TrimRight(string(boundaries), " "),
+		strings.Join(legend, ", "),
+	}, "\n") + "\n"
+}
+
+// RenderHTML writes a standalone HTML page to w containing an SVG timeline of the elements, see RenderSVG.
+func (x *Jailhouse[T]) RenderHTML(w io.Writer) error {
+	_, err := fmt.Fprintf(w, "<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n<title>keep timeline</title>\n</head>\n<body style=\"font-family: sans-serif\">\n<h1>keep timeline</h1>\n<p>%s</p>\n", html.EscapeString(x.requirements.String()))
+	if err != nil {
+		return err
+	}
+	err = x.RenderSVG(w)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprint(w, "</body>\n</html>\n")
+	return err
+}
+
+// RenderSVG writes an SVG timeline of the elements to w, oldest left, reference date right. Kept elements are
+// colored by their first level, free ones are grey, and the oldest element of every level is marked by a dashed
+// line. The time axis is logarithmic in the age of the elements.
+func (x *Jailhouse[T]) RenderSVG(w io.Writer) error {
+	const (
+		width   = 1200
+		margin  = 40
+		axisY   = 120
+		legendY = 200
+	)
+	var (
+		b        strings.Builder
+		position = x.timelinePosition()
+		xOf      = func(t time.Time) float64 { return margin + position(t)*(width-2*margin) }
+	)
+
+	fmt.Fprintf(&b, "<svg xmlns=\"http://www.w3.org/2000/svg\" width=\"%d\" height=\"%d\" font-family=\"sans-serif\" font-size=\"12\">\n", width, legendY+20*len(x.GetLevels()))
+	fmt.Fprintf(&b, "<line x1=\"%d\" y1=\"%d\" x2=\"%d\" y2=\"%d\" stroke=\"#333333\"/>\n", margin, axisY, width-margin, axisY)
+
+	oldest, youngest := x.timelineRange()
+	fmt.Fprintf(&b, "<text x=\"%d\" y=\"%d\">%s</text>\n", margin, axisY+30, oldest.Format("2006-01-02"))
+	fmt.Fprintf(&b, "<text x=\"%d\" y=\"%d\" text-anchor=\"end\">%s</text>\n", width-margin, axisY+30, youngest.Format("2006-01-02"))
+
+	// level boundaries
+	legendLine := 0
+	for _, level := range x.GetLevels() {
+		kept := x.KeptElementsByLevel(level)
+		if len(kept) == 0 {
+			continue
+		}
+		boundary := xOf(kept[len(kept)-1].GetTime())
+		fmt.Fprintf(&b, "<line x1=\"%.1f\" y1=\"%d\" x2=\"%.1f\" y2=\"%d\" stroke=\"%s\" stroke-dasharray=\"4 3\"/>\n", boundary, axisY-60, boundary, axisY+10, timelineColors[level])
+		fmt.Fprintf(&b, "<text x=\"%.1f\" y=\"%d\" fill=\"%s\">%s</text>\n", boundary+2, axisY-50, timelineColors[level], level)
+
+		fmt.Fprintf(&b, "<circle cx=\"%d\" cy=\"%d\" r=\"5\" fill=\"%s\"/>\n", margin, legendY+20*legendLine-4, timelineColors[level])
+		fmt.Fprintf(&b, "<text x=\"%d\" y=\"%d\">%s: %d</text>\n", margin+12, legendY+20*legendLine, level, len(kept))
+		legendLine++
+	}
+	fmt.Fprintf(&b, "<circle cx=\"%d\" cy=\"%d\" r=\"3\" fill=\"%s\"/>\n", margin, legendY+20*legendLine-4, timelineFreeColor)
+	fmt.Fprintf(&b, "<text x=\"%d\" y=\"%d\">free: %d</text>\n", margin+12, legendY+20*legendLine, len(x.FreeElements()))
+
+	// free elements first, so kept ones are drawn on top
+	for _, free := range []bool{true, false} {
+		for _, element := range x.elements {
+			if element.IsFree() != free {
+				continue
+			}
+			color, radius := timelineFreeColor, 3
+			if !free {
+				color, radius = timelineColors[element.GetTags()[0].TimeRange], 5
+			}
+			fmt.Fprintf(&b, "<circle cx=\"%.1f\" cy=\"%d\" r=\"%d\" fill=\"%s\"><title>%s</title></circle>\n", xOf(element.GetTime()), axisY, radius, color, html.EscapeString(fmt.Sprintf("%v\n%s", element.TimeResource, element)))
+		}
+	}
+	b.WriteString("</svg>\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// timelineRange returns the times at the left and right end of a timeline: the oldest element and the reference
+// date of the last evaluation (or the youngest element, if that is younger).
+func (x *Jailhouse[T]) timelineRange() (oldest, youngest time.Time) {
+	youngest = x.referenceDate
+	if len(x.elements) == 0 {
+		return youngest, youngest
+	}
+	if x.elements[0].GetTime().After(youngest) {
+		youngest = x.elements[0].GetTime()
+	}
+	return x.elements[len(x.elements)-1].GetTime(), youngest
+}
+
+// timelinePosition returns a function mapping a time to its relative position between 0 (oldest) and 1 (youngest)
+// on a logarithmic age axis.
+func (x *Jailhouse[T]) timelinePosition() func(time.Time) float64 {
+	oldest, youngest := x.timelineRange()
+	maxAge := math.Log1p(youngest.Sub(oldest).Minutes())
+	return func(t time.Time) float64 {
+		if maxAge == 0 {
+			return 1
+		}
+		age := youngest.Sub(t).Minutes()
+		if age < 0 {
+			age = 0
+		}
+		return 1 - math.Log1p(age)/maxAge
+	}
+}
@@ -0,0 +1,51 @@
+package keep
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func timelineTestJailhouse() *Jailhouse[File] {
+	testDate := time.Date(2024, time.January, 20, 0, 0, 0, 0, time.UTC)
+
+	x := NewDefaultJailhouse[File]()
+	x.AddElements(
+		File{Filename: "a", Time: date("2024-01-19").t},
+		File{Filename: "b", Time: date("2024-01-18").t},
+		File{Filename: "c", Time: date("2024-01-17").t},
+		File{Filename: "e", Time: date("2024-01-16").t},
+		File{Filename: "d <old>", Time: date("2023-01-17").t},
+	)
+	x.ApplyRequirementsForDate(*NewRequirements().Add(LAST, 1).Add(DAY, 1).Add(YEAR, 2), testDate)
+	return x
+}
+
+func TestJailhouse_RenderASCII(t *testing.T) {
+	lines := strings.Split(timelineTestJailhouse().RenderASCII(40), "\n")
+
+	assert.Equal(t, "2023-01-17                    2024-01-20", lines[0])
+	assert.Equal(t, 'Y', rune(lines[1][0]))
+	assert.Contains(t, lines[1], "L")
+	assert.Contains(t, lines[1], "D")
+	assert.Contains(t, lines[1], "-")
+	assert.Equal(t, '|', rune(lines[2][0]))
+	assert.Equal(t, "L=LAST (1), D=DAY (1), Y=YEAR (2), -=free (1)", lines[3])
+}
+
+func TestJailhouse_RenderHTML(t *testing.T) {
+	var b strings.Builder
+	err := timelineTestJailhouse().RenderHTML(&b)
+	assert.NoError(t, err)
+
+	output := b.String()
+	assert.True(t, strings.HasPrefix(output, "<!DOCTYPE html>"))
+	assert.Contains(t, output, "<svg")
+	assert.Contains(t, output, timelineColors[YEAR])
+	assert.Contains(t, output, timelineFreeColor)
+	assert.Contains(t, output, "d &lt;old&gt;")
+	// one circle per element and one per legend entry
+	assert.Equal(t, 5+4, strings.Count(output, "<circle"))
+}
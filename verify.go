@@ -0,0 +1,132 @@
+package keep
+
+import (
+	"fmt"
+	"time"
+)
+
+// ViolationKind describes which invariant of a Requirements definition is not met.
+type ViolationKind int8
+
+const (
+	// ViolationUnfilledLevel means a level holds fewer elements than required although older elements exist.
+	ViolationUnfilledLevel ViolationKind = iota
+	// ViolationGap means two consecutive elements of a level are further apart than the level step plus tolerance.
+	ViolationGap
+	// ViolationUnjustified means an element is not kept by any level.
+	ViolationUnjustified
+)
+
+var violationKindNames = map[ViolationKind]string{
+	ViolationUnfilledLevel: "unfilled level",
+	ViolationGap:           "gap",
+	ViolationUnjustified:   "unjustified",
+}
+
+// String implements the Stringer interface.
+func (x ViolationKind) String() string {
+	if name, ok := violationKindNames[x]; ok {
+		return name
+	}
+	return fmt.Sprintf("ViolationKind(%d)", x)
+}
+
+// Violation is a single finding of Jailhouse.Verify.
+type Violation[T TimeResource] struct {
+	Kind  ViolationKind
+	Level TimeRange
+	// Elements involved: the younger and the older element for gaps, the offending element otherwise.
+	// Empty for unfilled levels without any kept element.
+	Elements []*JailhouseTimeResource[T]
+	Message  string
+}
+
+func (x Violation[T]) String() string {
+	return fmt.Sprintf("%s: %s", x.Kind, x.Message)
+}
+
+// Verify applies the requirements for the given date and reports every violation found in the elements:
+// levels that could not be filled although older elements exist, gaps between consecutive elements of a level
+// larger than the level step plus tolerance, and elements no level keeps. The tolerance allows for backups not
+// running at exactly the same time every period, e.g. one hour for daily backups.
+// An empty result means the elements satisfy the requirements exactly.
+func (x *Jailhouse[T]) Verify(reqs Requirements, referenceDate time.Time, tolerance time.Duration) []Violation[T] {
+	x.ApplyRequirementsForDate(reqs, referenceDate)
+
+	violations := make([]Violation[T], 0)
+	var oldestKept *JailhouseTimeResource[T]
+	for _, level := range x.GetLevels() {
+		required := int(reqs.Get(level))
+		if required == 0 {
+			continue
+		}
+
+		kept := x.KeptElementsByLevel(level)
+		for i := 1; i < len(kept); i++ {
+			if !x.isGap(level, kept[i-1].GetTime(), kept[i].GetTime(), tolerance) {
+				continue
+			}
+			violations = append(violations, Violation[T]{
+				Kind:     ViolationGap,
+				Level:    level,
+				Elements: []*JailhouseTimeResource[T]{kept[i-1], kept[i]},
+				Message:  fmt.Sprintf("%s elements %s and %s are too far apart", level, kept[i-1].GetTime().Format(time.RFC3339), kept[i].GetTime().Format(time.RFC3339)),
+			})
+		}
+
+		if len(kept) > 0 && (oldestKept == nil || kept[len(kept)-1].GetTime().Before(oldestKept.GetTime())) {
+			oldestKept = kept[len(kept)-1]
+		}
+		if len(kept) < required && x.hasElementsOlderThan(oldestKept, referenceDate) {
+			violation := Violation[T]{
+				Kind:    ViolationUnfilledLevel,
+				Level:   level,
+				Message: fmt.Sprintf("%s holds %d of %d elements although older elements exist", level, len(kept), required),
+			}
+			if len(kept) > 0 {
+				violation.Elements = []*JailhouseTimeResource[T]{kept[len(kept)-1]}
+			}
+			violations = append(violations, violation)
+		}
+	}
+
+	for _, element := range x.FreeElements() {
+		violations = append(violations, Violation[T]{
+			Kind:     ViolationUnjustified,
+			Elements: []*JailhouseTimeResource[T]{element},
+			Message:  fmt.Sprintf("element %s is not kept by any level", element.GetTime().Format(time.RFC3339)),
+		})
+	}
+
+	return violations
+}
+
+// isGap reports whether older is too far from younger to follow it directly on the given level: more than one step
+// plus tolerance before it, or before the start of the preceding cell minus tolerance when anchored at the oldest
+// element.
+func (x *Jailhouse[T]) isGap(level TimeRange, younger, older time.Time, tolerance time.Duration) bool {
+	if level == LAST {
+		return false
+	}
+	limit := x.addLevelStep(level, younger)
+	if x.anchor == AnchorOldest {
+		epoch := x.epoch
+		if epoch.IsZero() {
+			epoch = x.elements[len(x.elements)-1].GetTime()
+		}
+		limit = x.addLevelStep(level, x.cellStart(level, epoch, younger))
+	}
+	return older.Before(limit.Add(-tolerance))
+}
+
+func (x *Jailhouse[T]) hasElementsOlderThan(element *JailhouseTimeResource[T], referenceDate time.Time) bool {
+	for _, e := range x.elements {
+		if e.GetTime().After(referenceDate) {
+			continue
+		}
+		if element == nil || e.GetTime().Before(element.GetTime()) {
+			return true
+		}
+	}
+	return false
+}